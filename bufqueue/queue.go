@@ -33,7 +33,10 @@ type Queue struct {
 	// pendingEnqueued is a slice of pointers to check.Check structs that are awaiting official enquement by the
 	// CheckEnqueuer.
 	pendingEnqueued []*check.Check
-	// mu guards pendingEnqueued
+	// stored is closed once the CheckEnqueuer has stored checks, to wake DequeueWait callers waiting on the
+	// CheckProvider.  It is nil while no caller is waiting.
+	stored chan struct{}
+	// mu guards pendingEnqueued and stored
 	mu sync.Mutex
 
 	// CheckProvider provides the check.Check structs when the outgoing check.Check queue/buffer is empty during a
@@ -47,6 +50,13 @@ type Queue struct {
 
 	// CheckEnqueuerInterval is a time.Duration indicating how often to execute the CheckEnqueuer.
 	CheckEnqueuerInterval time.Duration
+
+	// ProviderPollInterval is how long DequeueWait() waits for a check in the outgoing buffer to come due before
+	// asking the CheckProvider for checks again (when the buffer is empty).  DequeueWait() also asks again as soon as
+	// the CheckEnqueuer has stored checks enqueued through this Queue, so the interval bounds how late a check that
+	// reaches the backend any other way is seen: a due check the CheckProvider starts providing is dequeued up to
+	// ProviderPollInterval after it could have been.
+	ProviderPollInterval time.Duration
 }
 
 func NewQueue(
@@ -60,6 +70,7 @@ func NewQueue(
 		CheckProvider:         checkProvider,
 		CheckEnqueuer:         checkEnqueuer,
 		CheckEnqueuerInterval: checkEnqueuerInterval,
+		ProviderPollInterval:  time.Second,
 	}
 
 	q.runCheckEnqueuer(ctx)
@@ -74,13 +85,57 @@ func (q *Queue) Enqueue(chk *check.Check) {
 }
 
 func (q *Queue) Dequeue() *check.Check {
-	if q.queue.Count() == 0 { // underlying memqueue is empty, so fill it with checks from the provider
+	q.fill()
+
+	return q.queue.Dequeue()
+}
+
+// DequeueWait blocks until a check in the outgoing buffer is due and returns it, or returns nil once ctx is done.
+// While the buffer is empty, the CheckProvider is asked for checks every ProviderPollInterval and whenever the
+// CheckEnqueuer has stored checks.
+func (q *Queue) DequeueWait(ctx context.Context) *check.Check {
+	for {
+		q.mu.Lock()
+		if q.stored == nil {
+			q.stored = make(chan struct{})
+		}
+		stored := q.stored
+		q.mu.Unlock()
+
+		q.fill()
+
+		waitCtx, cancel := context.WithTimeout(ctx, q.ProviderPollInterval)
+		go func() {
+			select {
+			case <-stored:
+				cancel()
+			case <-waitCtx.Done():
+			}
+		}()
+		chk := q.queue.DequeueWait(waitCtx)
+		cancel()
+
+		if chk != nil {
+			return chk
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// NextDueAt returns when the next check in the outgoing buffer is due, or false if the buffer is empty.
+func (q *Queue) NextDueAt() (time.Time, bool) {
+	return q.queue.NextDueAt()
+}
+
+// fill fills the outgoing buffer with checks from the CheckProvider if it is empty.
+func (q *Queue) fill() {
+	if q.queue.Count() == 0 {
 		for _, chk := range q.CheckProvider.Provide() {
 			q.queue.Enqueue(chk)
 		}
 	}
-
-	return q.queue.Dequeue()
 }
 
 func (q *Queue) Count() uint64 {
@@ -98,6 +153,17 @@ func (q *Queue) Flush() {
 	}
 }
 
+// All returns every check in the outgoing buffer.
+func (q *Queue) All() []*check.Check {
+	return q.queue.All()
+}
+
+// Remove removes the check with the given ID from the outgoing buffer and returns it, or returns nil if it is not in
+// the buffer.
+func (q *Queue) Remove(id string) *check.Check {
+	return q.queue.Remove(id)
+}
+
 // runCheckEnqueuer kicks off goroutine to periodically call the CheckEnqueuer
 func (q *Queue) runCheckEnqueuer(ctx context.Context) {
 	ticker := time.NewTicker(q.CheckEnqueuerInterval)
@@ -127,5 +193,17 @@ func (q *Queue) enqueuePending() {
 
 	if len(chks) > 0 {
 		q.CheckEnqueuer.Enqueue(chks)
+		q.wake()
+	}
+}
+
+// wake wakes any DequeueWait callers waiting on the CheckProvider.
+func (q *Queue) wake() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.stored != nil {
+		close(q.stored)
+		q.stored = nil
 	}
 }
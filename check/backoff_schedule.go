@@ -0,0 +1,119 @@
+package check
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// DefaultBackoffFactor is the factor a BackoffSchedule's interval is
+	// multiplied by for each consecutive failure when Factor is not set.
+	DefaultBackoffFactor = 2.0
+
+	// DefaultBackoffMaxInterval caps a BackoffSchedule's interval when
+	// MaxInterval is not set.
+	DefaultBackoffMaxInterval = time.Hour
+)
+
+// BackoffSchedule is a Schedule decorator that backs off from Checks that
+// keep failing.  While the Check's LastResult is failing, the interval
+// between LastCheck and the wrapped Schedule's DueAt is multiplied by Factor
+// for every consecutive failure after the first, up to MaxInterval.  As soon
+// as the Check stops failing, the wrapped Schedule applies as-is.
+//
+// A Result is failing when it is non-OK, its state is in States (if set) and
+// its reason code is in ReasonCodes (if set).  Consecutive failures are
+// counted from the Check's History when it has one, so only failing Results
+// count.  Without a History, Check.FailureCount (every consecutive non-OK
+// Result) is used.
+type BackoffSchedule struct {
+	// Schedule is the wrapped Schedule.
+	Schedule Schedule `json:"-"`
+
+	// Factor is what the interval is multiplied by per consecutive failure.
+	// Zero means DefaultBackoffFactor, and 1 disables backoff.  Factors less
+	// than 1 are treated as 1 so that the interval never shrinks.
+	Factor float64 `json:"factor,omitempty"`
+
+	// MaxInterval is the longest the interval may grow to.
+	MaxInterval time.Duration `json:"maxInterval,omitempty"`
+
+	// States limits backoff to Results with these states.
+	States []ResultState `json:"states,omitempty"`
+
+	// ReasonCodes limits backoff to Results with these reason codes.
+	ReasonCodes []string `json:"reasonCodes,omitempty"`
+}
+
+// NewBackoffSchedule wraps schedule in a BackoffSchedule that backs off from
+// any non-OK Result.  factor must be 0 (for DefaultBackoffFactor) or at least
+// 1.
+func NewBackoffSchedule(schedule Schedule, factor float64, maxInterval time.Duration) (*BackoffSchedule, error) {
+	if factor != 0 && factor < 1 {
+		return nil, fmt.Errorf("invalid backoff factor %v: must be at least 1", factor)
+	}
+	return &BackoffSchedule{
+		Schedule:    schedule,
+		Factor:      factor,
+		MaxInterval: maxInterval,
+	}, nil
+}
+
+func (s *BackoffSchedule) DueAt(check *Check) time.Time {
+	dueAt := s.Schedule.DueAt(check)
+	if check.LastCheck == nil || check.LastResult == nil || !s.isFailing(check.LastResult) {
+		return dueAt
+	}
+
+	interval := dueAt.Sub(*check.LastCheck)
+	if interval <= 0 {
+		return dueAt
+	}
+
+	factor := s.Factor
+	if factor == 0 {
+		factor = DefaultBackoffFactor
+	} else if factor < 1 {
+		factor = 1
+	}
+	maxInterval := s.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = DefaultBackoffMaxInterval
+	}
+
+	backoff := float64(interval) * math.Pow(factor, float64(s.failures(check)-1))
+	if backoff > float64(maxInterval) {
+		// never shorten the wrapped schedule's own interval
+		return check.LastCheck.Add(max(maxInterval, interval))
+	}
+	return check.LastCheck.Add(time.Duration(backoff))
+}
+
+// failures returns the number of consecutive failing Results, including the
+// Check's LastResult (which is failing).
+func (s *BackoffSchedule) failures(check *Check) int {
+	if check.History == nil || check.History.Len() == 0 {
+		return max(check.FailureCount, 1)
+	}
+
+	n := 0
+	for i := check.History.Len() - 1; i >= 0 && s.isFailing(check.History.At(i)); i-- {
+		n++
+	}
+	return max(n, 1)
+}
+
+func (s *BackoffSchedule) isFailing(result *Result) bool {
+	if result.State == StateOk {
+		return false
+	}
+	if len(s.States) > 0 && !slices.Contains(s.States, result.State) {
+		return false
+	}
+	if len(s.ReasonCodes) > 0 && !slices.Contains(s.ReasonCodes, result.ReasonCode) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package check
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffSchedule_DueAt(t *testing.T) {
+	lastCheck := time.Now()
+	s, err := NewBackoffSchedule(&PeriodicSchedule{IntervalSeconds: 60}, 2, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("NewBackoffSchedule(): unexpected error: %v", err)
+	}
+	s.ReasonCodes = []string{"CONNECTION_ERROR"}
+
+	connErr := func(state ResultState) *Result {
+		return &Result{State: state, ReasonCode: "CONNECTION_ERROR", Time: lastCheck}
+	}
+	latency := &Result{State: StateCrit, ReasonCode: "LATENCY_HIGH", Time: lastCheck}
+
+	tests := []struct {
+		name         string
+		lastResult   *Result
+		failureCount int
+		history      []*Result
+		want         time.Duration
+	}{
+		{name: "no last result", lastResult: nil, want: 60 * time.Second},
+		{name: "ok", lastResult: &Result{State: StateOk}, failureCount: 0, want: 60 * time.Second},
+		{name: "first failure", lastResult: connErr(StateUnknown), failureCount: 1, want: 60 * time.Second},
+		{name: "second failure", lastResult: connErr(StateUnknown), failureCount: 2, want: 120 * time.Second},
+		{name: "third failure", lastResult: connErr(StateUnknown), failureCount: 3, want: 240 * time.Second},
+		{name: "capped", lastResult: connErr(StateUnknown), failureCount: 50, want: 5 * time.Minute},
+		{name: "other reason", lastResult: latency, failureCount: 3, want: 60 * time.Second},
+		{
+			name:       "state change keeps backing off",
+			lastResult: connErr(StateCrit),
+			history:    []*Result{connErr(StateUnknown), connErr(StateUnknown), connErr(StateCrit)},
+			want:       240 * time.Second,
+		},
+		{
+			name:       "other reason in history resets backoff",
+			lastResult: connErr(StateCrit),
+			history:    []*Result{connErr(StateCrit), latency, connErr(StateCrit)},
+			want:       60 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		c := &Check{Schedule: s, LastCheck: &lastCheck, LastResult: tt.lastResult, FailureCount: tt.failureCount}
+		if tt.history != nil {
+			c.History = NewResultHistory(10, 0)
+			for _, r := range tt.history {
+				c.History.Add(r)
+			}
+		}
+		if got := c.DueAt().Sub(lastCheck); got != tt.want {
+			t.Errorf("%s: DueAt() is %v after last check, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffSchedule_Factor(t *testing.T) {
+	lastCheck := time.Now()
+	failing := &Result{State: StateCrit}
+
+	tests := []struct {
+		name   string
+		factor float64
+		want   time.Duration
+	}{
+		{name: "default", factor: 0, want: 240 * time.Second},
+		{name: "disabled", factor: 1, want: 60 * time.Second},
+		{name: "less than 1", factor: 0.5, want: 60 * time.Second},
+		{name: "custom", factor: 3, want: 540 * time.Second},
+	}
+
+	for _, tt := range tests {
+		s := &BackoffSchedule{Schedule: &PeriodicSchedule{IntervalSeconds: 60}, Factor: tt.factor}
+		c := &Check{Schedule: s, LastCheck: &lastCheck, LastResult: failing, FailureCount: 3}
+		if got := c.DueAt().Sub(lastCheck); got != tt.want {
+			t.Errorf("%s: DueAt() is %v after last check, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := NewBackoffSchedule(&PeriodicSchedule{IntervalSeconds: 60}, 0.5, 0); err == nil {
+		t.Error("NewBackoffSchedule(): expected an error for a factor less than 1")
+	}
+	if _, err := NewBackoffSchedule(&PeriodicSchedule{IntervalSeconds: 60}, 1, 0); err != nil {
+		t.Errorf("NewBackoffSchedule(): unexpected error for a factor of 1: %v", err)
+	}
+}
+
+func TestCheck_ExecuteCountsConsecutiveFailures(t *testing.T) {
+	cmd := &stateCommand{state: StateUnknown}
+	c := &Check{Command: cmd}
+
+	_ = c.Execute()
+	cmd.state = StateCrit
+	_ = c.Execute()
+	if c.FailureCount != 2 {
+		t.Errorf("Execute(): expected 2 consecutive failures across a state change, got %d", c.FailureCount)
+	}
+
+	cmd.state = StateOk
+	_ = c.Execute()
+	if c.FailureCount != 0 {
+		t.Errorf("Execute(): expected failures reset by an OK result, got %d", c.FailureCount)
+	}
+}
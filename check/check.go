@@ -1,12 +1,14 @@
 package check
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/hashicorp/go-multierror"
+	"log/slog"
 	"reflect"
-	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,38 +16,38 @@ import (
 // a given Schedule.
 type Check struct {
 	// Id should be any unique value for this check.
-	Id string
+	Id string `json:"id"`
 
 	// Schedule determines when this Check is due to be executed.
-	Schedule Schedule
+	Schedule Schedule `json:"schedule,omitempty"`
 
 	// Command is the command this check runs against the service or host.
 	// Examples are snmp, ping, dns, or http commands
-	Command Command
+	Command Command `json:"command,omitempty"`
 
 	// Meta is a key/value store of any extra data you want to live with the
 	// check.
-	Meta map[string]any
+	Meta map[string]any `json:"meta,omitempty"`
 
 	// Incident needs to be the current active incident for this check
 	// or else nil.
-	Incident *Incident
+	Incident *Incident `json:"incident,omitempty"`
 
 	// SuppressIncidents set to true means when this Check executes and
 	// produces an Incident, it discards it.
-	SuppressIncidents bool
+	SuppressIncidents bool `json:"suppressIncidents,omitempty"`
 
 	// Handlers is a slice of handlers to execute after the Check's Command runs.
 	// A Handler has a Mutate() and Process() method for mutating a Check's data
 	// and processing it, respectfully.  Mutate() methods are called first in
 	// sequential order as specified in this slice.  Then Process() methods are
 	// called asynchronously.
-	Handlers []Handler
+	Handlers []Handler `json:"handlers,omitempty"`
 
 	// LastCheck is a time.Time of the last time this Check executed. This will
 	// be updated automatically by Execute(), but be sure it's set to the
 	// correct time when loading a check from an external database.
-	LastCheck *time.Time
+	LastCheck *time.Time `json:"lastCheck,omitempty"`
 
 	// LastResult is a Result from the last time this Check executed (or nil).
 	// This will be updated automatically by Execute(), but be sure it's set to
@@ -54,21 +56,118 @@ type Check struct {
 	// LastResult could be used by the Command to determine value deltas.  If
 	// you are certain this is not required in your case, then this could always
 	// be nil.
-	LastResult *Result
+	LastResult *Result `json:"lastResult,omitempty"`
 
-	// debugLogger is called by Debug. Commands and Handlers call the Check's
-	// Debugf() method with debugging information.  This is generally nil unless
-	// you want to debug a particular Check.
-	debugLogger debugLogger
+	// logger is the Check's structured logger (see Logger()) and debug is
+	// non-zero while debug logging is enabled for the Check (see SetDebug()).
+	logger *slog.Logger
+	debug  int32
+
+	// env is the Environment the Check's Command and Handlers run in (see
+	// Environment()).
+	env *Environment
+
+	// debugLogger is the deprecated alternative to logger set by
+	// WithDebugLogger().
+	debugLogger DebugLogger
 
 	// Executed is true when the Check has had Execute() called on it.  You should
 	// set this back to false prior to queueing it again.
-	Executed bool
+	Executed bool `json:"executed,omitempty"`
+
+	// Timeout is the maximum duration the Check's Command may run.  Once it
+	// passes, the Command is abandoned and an UNKNOWN Result with reason code
+	// TIMEOUT takes its place.  Zero means the Command may run indefinitely
+	// (or up to the server.Server's default timeout).
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// MaxAttempts is the number of consecutive non-OK results required before
+	// the Check's state turns hard and an Incident may be opened.  Until then
+	// the state is soft.  Values less than 2 make every state hard.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	// RetryInterval, when non-zero, is used in place of the Schedule to
+	// determine when the Check is next due while its state is soft.
+	RetryInterval time.Duration `json:"retryInterval,omitempty"`
+
+	// Attempt is the number of consecutive non-OK results (up to MaxAttempts)
+	// or 0 when the last result was OK.  This will be updated automatically by
+	// Execute(), but be sure it's set when loading a check from an external
+	// database.
+	Attempt int `json:"attempt,omitempty"`
+
+	// StateType is whether the state of LastResult is soft or hard.  This will
+	// be updated automatically by Execute(), but be sure it's set when loading
+	// a check from an external database.
+	StateType StateType `json:"stateType"`
+
+	// StateCount is the number of consecutive executions, including the last,
+	// whose Result had the same state as LastResult.  This will be updated
+	// automatically by Execute(), but be sure it's set when loading a check
+	// from an external database.
+	StateCount int `json:"stateCount,omitempty"`
+
+	// FailureCount is the number of consecutive executions, including the
+	// last, whose Result was non-OK.  This will be updated automatically by
+	// Execute(), but be sure it's set when loading a check from an external
+	// database.
+	FailureCount int `json:"failureCount,omitempty"`
+
+	// FlapDetection, when non-nil, enables flap detection for the Check.
+	// While a Check is flapping, no new state Incidents are opened.  Instead,
+	// a single flapping Incident is opened when flapping starts and resolved
+	// when it stops.
+	FlapDetection *FlapDetection `json:"flapDetection,omitempty"`
+
+	// StateHistory is the window of recent result states (oldest first) that
+	// flap detection is computed over.  This will be updated automatically by
+	// Execute(), but be sure it's set when loading a check from an external
+	// database.
+	StateHistory []ResultState `json:"stateHistory,omitempty"`
+
+	// IsFlapping is true while the Check's state is flapping.
+	IsFlapping bool `json:"isFlapping,omitempty"`
+
+	// PercentStateChange is the weighted percent state change of StateHistory
+	// as of the last execution.
+	PercentStateChange float64 `json:"percentStateChange,omitempty"`
+
+	// DependsOn is a list of IDs of parent Checks this Check depends on.  When
+	// any parent is down, the Check's Command is not run and an UNKNOWN Result
+	// with reason code DEPENDENCY_UNREACHABLE is produced instead, which never
+	// opens an Incident.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// DependencyResolver resolves DependsOn into the parent Checks' last
+	// Results.  DependsOn is ignored while this is nil.
+	DependencyResolver DependencyResolver `json:"-"`
+
+	// DowntimeProvider provides the Check's scheduled downtime, if any.
+	DowntimeProvider DowntimeProvider `json:"-"`
+
+	// InDowntime is true if the Check was in downtime when it last executed.
+	InDowntime bool `json:"inDowntime,omitempty"`
+
+	// ContinuousIncidents, when true, keeps a single Incident open while the
+	// Check moves between non-OK states.  Each change is recorded on the
+	// Incident with Escalate() instead of resolving it and opening another.
+	ContinuousIncidents bool `json:"continuousIncidents,omitempty"`
+
+	// RecoveryThreshold is the number of consecutive OK results required to
+	// resolve the Check's Incident.  Values less than 2 resolve it on the
+	// first OK result.
+	RecoveryThreshold int `json:"recoveryThreshold,omitempty"`
+
+	// History, when non-nil, keeps the Check's recent Results for Commands
+	// and Handlers that need more than LastResult (ex. rates or trends).  This
+	// will be updated automatically by Execute(), but be sure it's set when
+	// loading a check from an external database.
+	History *ResultHistory `json:"history,omitempty"`
 }
 
-type debugLogger interface {
-	Debugf(format string, args ...any)
-}
+// ErrTimeout is returned (wrapped) by Execute when the Check's Command did not
+// finish within its Timeout.
+var ErrTimeout = errors.New("check command timed out")
 
 type Option func(*Check)
 
@@ -121,70 +220,177 @@ func WithSchedule(schedule Schedule) Option {
 	}
 }
 
-func WithDebugLogger(logger debugLogger) Option {
+func WithTimeout(timeout time.Duration) Option {
 	return func(c *Check) {
-		c.debugLogger = logger
+		c.Timeout = timeout
 	}
 }
 
-func (c *Check) SetDebugLogger(logger debugLogger) {
-	c.debugLogger = logger
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(c *Check) {
+		c.MaxAttempts = maxAttempts
+	}
 }
 
-// DueAt returns the time when check is due (could be past or future)
-func (c *Check) DueAt() time.Time {
-	return c.Schedule.DueAt(c)
+func WithRetryInterval(retryInterval time.Duration) Option {
+	return func(c *Check) {
+		c.RetryInterval = retryInterval
+	}
 }
 
-// IsDue returns true if the check is due for execution
-func (c *Check) IsDue() bool {
-	return c.DueAt().Compare(time.Now()) <= 0
+func WithFlapDetection(lowThreshold, highThreshold float64) Option {
+	return func(c *Check) {
+		c.FlapDetection = &FlapDetection{
+			LowThreshold:  lowThreshold,
+			HighThreshold: highThreshold,
+			WindowSize:    DefaultFlapWindowSize,
+		}
+	}
 }
 
-// Debugf should be used liberally by Commands and Handlers to provide debugging information.
-func (c *Check) Debugf(format string, args ...any) {
-	if c.debugLogger != nil {
-		formatPrefix := fmt.Sprintf("[ID:%s] ", c.Id)
+func WithDependsOn(ids ...string) Option {
+	return func(c *Check) {
+		c.DependsOn = ids
+	}
+}
 
-		// get the caller's information
-		pc, _, _, ok := runtime.Caller(1)
-		if ok {
-			formatPrefix += fmt.Sprintf("[%s] ", runtime.FuncForPC(pc).Name())
-		}
+func WithDependencyResolver(resolver DependencyResolver) Option {
+	return func(c *Check) {
+		c.DependencyResolver = resolver
+	}
+}
+
+func WithDowntimeProvider(provider DowntimeProvider) Option {
+	return func(c *Check) {
+		c.DowntimeProvider = provider
+	}
+}
+
+func WithContinuousIncidents() Option {
+	return func(c *Check) {
+		c.ContinuousIncidents = true
+	}
+}
+
+func WithRecoveryThreshold(recoveryThreshold int) Option {
+	return func(c *Check) {
+		c.RecoveryThreshold = recoveryThreshold
+	}
+}
+
+// WithResultHistory keeps up to size recent Results, no older than maxAge (if
+// non-zero), in the Check's History.
+func WithResultHistory(size int, maxAge time.Duration) Option {
+	return func(c *Check) {
+		c.History = NewResultHistory(size, maxAge)
+	}
+}
 
-		c.debugLogger.Debugf(formatPrefix+format, args...)
+// DueAt returns the time when check is due (could be past or future).  While
+// the Check is in a soft state, it is due RetryInterval after LastCheck if a
+// RetryInterval is set.
+func (c *Check) DueAt() time.Time {
+	if c.StateType == StateTypeSoft && c.RetryInterval > 0 && c.LastCheck != nil {
+		return c.LastCheck.Add(c.RetryInterval)
 	}
+	return c.Schedule.DueAt(c)
+}
+
+// IsDue returns true if the check is due for execution
+func (c *Check) IsDue() bool {
+	return c.DueAt().Compare(c.Environment().Now()) <= 0
 }
 
 // Execute executes a Check's Command followed by its Handlers.  It then sets the Incident (if there is one),
 // LastCheck and LastResult fields on the Check.
 func (c *Check) Execute() error {
+	return c.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is like Execute but passes ctx through to the Check's Command and Handlers so that cancellation and
+// deadlines propagate into any in-flight I/O.  Commands and Handlers that do not implement ContextCommand or
+// ContextHandler are adapted with CommandWithContext and HandlerWithContext.
+func (c *Check) ExecuteContext(ctx context.Context) error {
+	return c.ExecuteWithTimeout(ctx, c.Timeout)
+}
+
+// ExecuteWithTimeout is like ExecuteContext but abandons the Command after timeout rather than after the Check's own
+// Timeout.  An abandoned Command produces an UNKNOWN Result with reason code TIMEOUT which is handled like any other
+// Result, and the returned error wraps ErrTimeout.  A timeout <= 0 disables this.
+func (c *Check) ExecuteWithTimeout(ctx context.Context, timeout time.Duration) error {
 	c.Executed = true
 
 	var result *Result
 	var err error
-	if c.Command == nil {
-		result, err = MakeUnknownResult("CMD_FAILURE"), errors.New("command not defined in check")
+	var duration time.Duration
+	if parentId := c.unreachableVia(); parentId != "" {
+		c.Debugf("parent check %s is down, not running command", parentId)
+		result = MakeUnknownResult("DEPENDENCY_UNREACHABLE")
+		result.UnreachableVia = parentId
 	} else {
-		result, err = c.Command.Run(c)
+		startTime := c.Environment().Now()
+		result, err = c.runCommand(ctx, timeout)
+		duration = c.Environment().Now().Sub(startTime)
+	}
+	// results are timed by the Check's Environment rather than by whatever created them
+	result.Time = c.Environment().Now()
+	c.runResultMutators(result)
+	c.setResultAttempt(result)
+	if errD := c.setResultDowntime(result); errD != nil {
+		err = multierror.Append(err, errD)
 	}
 
-	c.Debugf("result-state=%s result-reason-code=%s result-metrics=%d result-time=%d",
-		result.State.String(), result.ReasonCode, len(result.Metrics), result.Time.Unix())
+	c.logDebug(ctx, 2, "check result",
+		slog.String("state", result.State.String()),
+		slog.String("state_type", result.StateType.String()),
+		slog.Int("attempt", result.Attempt),
+		slog.String("reason", result.ReasonCode),
+		slog.Int("metrics", len(result.Metrics)),
+		slog.Duration("duration", duration),
+	)
+
+	if c.Incident != nil {
+		c.Incident.expireAcknowledgement(c.Environment().Now())
+	}
 
-	newIncident := c.makeNewIncidentIfJustified(result)
+	var newIncident *Incident
+	if c.detectFlapping(result) && !c.SuppressIncidents && !result.InDowntime {
+		newIncident = makeFlappingIncidentAt(c.LastResult, result, result.Time)
+	} else {
+		newIncident = c.makeNewIncidentIfJustified(result)
+	}
 	c.Debugf("new-incident=%v", newIncident != nil)
 	c.resolveOrDiscardPreviousIncident(result, newIncident)
 
 	c.runResultHandlerMutations(result, newIncident)
-	errP := c.runResultHandlerProcessing(result, newIncident)
+	errP := c.runResultHandlerProcessing(ctx, result, newIncident)
 	if errP != nil {
 		err = multierror.Append(err, errP)
 	}
 
-	t := time.Now()
+	if c.LastResult != nil && c.LastResult.State == result.State {
+		c.StateCount++
+	} else {
+		c.StateCount = 1
+	}
+	if result.State != StateOk {
+		c.FailureCount++
+	} else {
+		c.FailureCount = 0
+	}
+
+	t := c.Environment().Now()
 	c.LastCheck = &t
 	c.LastResult = result
+	c.Attempt = result.Attempt
+	c.StateType = result.StateType
+	c.InDowntime = result.InDowntime
+	if c.History != nil {
+		c.History.Add(result)
+	}
+	if c.DependencyResolver != nil {
+		c.DependencyResolver.Record(c, result)
+	}
 	if newIncident != nil {
 		c.Incident = newIncident
 	}
@@ -192,13 +398,102 @@ func (c *Check) Execute() error {
 	return err
 }
 
+// runCommand runs the Check's Command, abandoning it if it runs longer than timeout.
+func (c *Check) runCommand(ctx context.Context, timeout time.Duration) (*Result, error) {
+	if c.Command == nil {
+		return MakeUnknownResult("CMD_FAILURE"), errors.New("command not defined in check")
+	}
+
+	cmd := CommandWithContext(c.Command)
+	if timeout <= 0 {
+		return cmd.RunContext(ctx, c)
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// the Command may be abandoned while it still runs, after which the Check executes again.  so rather than the
+	// Check itself, it is given a snapshot that is never touched again
+	chk := c.commandSnapshot()
+
+	type runReturn struct {
+		result *Result
+		err    error
+	}
+	// buffered so an abandoned Command's goroutine can still exit when it eventually returns
+	returnCh := make(chan runReturn, 1)
+	go func() {
+		result, err := cmd.RunContext(cmdCtx, chk)
+		returnCh <- runReturn{result, err}
+	}()
+
+	select {
+	case r := <-returnCh:
+		// a Command that returned because of our deadline is treated as timed out
+		if ctx.Err() != nil || !errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+			return r.result, r.err
+		}
+	case <-cmdCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return MakeUnknownResult("CMD_FAILURE"), err
+		}
+	}
+
+	c.Debugf("command abandoned after %s", timeout)
+	return MakeUnknownResult("TIMEOUT"), fmt.Errorf("%w after %s", ErrTimeout, timeout)
+}
+
+// commandSnapshot returns a copy of the Check's fields that Commands read, with its own copies of Meta and History.
+func (c *Check) commandSnapshot() *Check {
+	snapshot := &Check{
+		Id:            c.Id,
+		Schedule:      c.Schedule,
+		Command:       c.Command,
+		LastResult:    c.LastResult,
+		Timeout:       c.Timeout,
+		MaxAttempts:   c.MaxAttempts,
+		RetryInterval: c.RetryInterval,
+		Attempt:       c.Attempt,
+		StateType:     c.StateType,
+		StateCount:    c.StateCount,
+		FailureCount:  c.FailureCount,
+		InDowntime:    c.InDowntime,
+		logger:        c.logger,
+		debug:         atomic.LoadInt32(&c.debug),
+		env:           c.env,
+		debugLogger:   c.debugLogger,
+	}
+	if c.Meta != nil {
+		snapshot.Meta = make(map[string]any, len(c.Meta))
+		for k, v := range c.Meta {
+			snapshot.Meta[k] = v
+		}
+	}
+	if c.LastCheck != nil {
+		t := *c.LastCheck
+		snapshot.LastCheck = &t
+	}
+	if c.History != nil {
+		snapshot.History = c.History.clone()
+	}
+	return snapshot
+}
+
+func (c *Check) runResultMutators(result *Result) {
+	for _, h := range c.Handlers {
+		if m, ok := h.(ResultMutator); ok {
+			m.MutateResult(c, result)
+		}
+	}
+}
+
 func (c *Check) runResultHandlerMutations(result *Result, newIncident *Incident) {
 	for _, h := range c.Handlers {
 		h.Mutate(c, result, newIncident)
 	}
 }
 
-func (c *Check) runResultHandlerProcessing(result *Result, newIncident *Incident) error {
+func (c *Check) runResultHandlerProcessing(ctx context.Context, result *Result, newIncident *Incident) error {
 	if c.Handlers == nil {
 		return nil
 	}
@@ -210,14 +505,10 @@ func (c *Check) runResultHandlerProcessing(result *Result, newIncident *Incident
 		go func(h Handler) {
 			defer wg.Done()
 
-			err := h.Process(c, result, newIncident)
+			err := HandlerWithContext(h).ProcessContext(ctx, c, result, newIncident)
 
 			if err != nil {
-				t := reflect.TypeOf(h)
-				if t.Kind() == reflect.Ptr {
-					t = t.Elem()
-				}
-				errorCh <- fmt.Errorf("error in handler '%s': %v", t.PkgPath()+"."+t.Name(), err)
+				errorCh <- &HandlerError{Handler: h, Err: err}
 			}
 		}(h)
 	}
@@ -236,24 +527,104 @@ func (c *Check) runResultHandlerProcessing(result *Result, newIncident *Incident
 	return errs
 }
 
+// setResultAttempt sets the attempt number and soft/hard state type of result based on the Check's previous attempt.
+func (c *Check) setResultAttempt(result *Result) {
+	// an unreachable Check is not itself a problem
+	if result.State == StateOk || result.UnreachableVia != "" {
+		result.Attempt, result.StateType = 0, StateTypeHard
+		return
+	}
+
+	maxAttempts := max(c.MaxAttempts, 1)
+	lastNonOk := c.LastResult != nil && c.LastResult.State != StateOk && c.LastResult.UnreachableVia == ""
+	if lastNonOk && c.StateType == StateTypeHard {
+		// changes between non-OK states are hard once the problem itself is hard
+		result.Attempt, result.StateType = maxAttempts, StateTypeHard
+		return
+	}
+
+	result.Attempt = 1
+	if lastNonOk {
+		result.Attempt = c.Attempt + 1
+	}
+	if result.Attempt >= maxAttempts {
+		result.Attempt, result.StateType = maxAttempts, StateTypeHard
+	} else {
+		result.StateType = StateTypeSoft
+	}
+}
+
+// setResultDowntime marks result as InDowntime if the Check's DowntimeProvider says it is.
+func (c *Check) setResultDowntime(result *Result) error {
+	if c.DowntimeProvider == nil {
+		return nil
+	}
+
+	downtime, err := c.DowntimeProvider.ActiveDowntime(c, result.Time)
+	if err != nil {
+		return fmt.Errorf("error getting downtime: %w", err)
+	}
+	if downtime != nil {
+		c.Debugf("in downtime %s (%s)", downtime.Id, downtime.Comment)
+		result.InDowntime = true
+	}
+	return nil
+}
+
 func (c *Check) makeNewIncidentIfJustified(result *Result) *Incident {
 	if !result.justifiesNewIncidentForCheck(c) {
 		return nil
 	}
 
-	i := MakeIncidentFromResults(c.LastResult, result)
+	if c.ContinuousIncidents && c.Incident != nil && !c.Incident.IsResolved() && c.Incident.Type == IncidentTypeState {
+		c.Debugf("escalating incident from %s to %s", c.Incident.ToState, result.State)
+		c.Incident.EscalateAt(result.Time, result.State, result.ReasonCode)
+		return nil
+	}
+
+	lastResult := c.LastResult
+	if lastResult != nil && lastResult.StateType == StateTypeSoft {
+		// soft states only ever follow an OK state, which is what the incident really transitioned from
+		lastResult = &Result{State: StateOk}
+	}
+
+	i := makeIncidentAt(lastResult, result, result.Time)
 	return i
 }
 
+// hasRecovered returns true if the current OK result makes RecoveryThreshold consecutive OK results.
+func (c *Check) hasRecovered() bool {
+	consecutive := 1
+	if c.LastResult != nil && c.LastResult.State == StateOk {
+		consecutive += c.StateCount
+	}
+	return consecutive >= c.RecoveryThreshold
+}
+
 // resolveOrDiscardPreviousIncident takes a new result and incident and determines if an old incident within the
 // check should be resolved or discarded.
 func (c *Check) resolveOrDiscardPreviousIncident(newResult *Result, newIncident *Incident) {
-	// if an existing incident exists and the current state is OK or there is now a new incident
-	if c.Incident != nil && (newResult.State == StateOk || newIncident != nil) {
+	if c.Incident == nil {
+		return
+	}
+
+	var finished bool
+	if c.Incident.Type == IncidentTypeFlapping {
+		// a flapping incident lasts as long as the flapping does, regardless of state
+		finished = newIncident != nil || !c.IsFlapping
+	} else {
+		finished = newResult.State == StateOk && c.hasRecovered() || newIncident != nil
+	}
+
+	// if the existing incident is finished because the current state is OK or there is now a new incident
+	if finished {
 		if c.Incident.Resolved == nil {
+			if newIncident != nil && newIncident.Type == c.Incident.Type {
+				newIncident.inheritAcknowledgement(c.Incident, newResult.Time)
+			}
 			// resolve it since we are now OK or have new incident
 			c.Debugf("resolving previous incident")
-			c.Incident.Resolve()
+			c.Incident.ResolveAt(newResult.Time)
 		} else {
 			// already resolved(old incident), discard it
 			c.Debugf("discarding previous incident")
@@ -268,6 +639,39 @@ type Command interface {
 	Run(*Check) (*Result, error)
 }
 
+// ContextCommand is a Command that can also be run with a context.Context.
+// The context is cancelled when the server shuts down or the Check's deadline
+// passes, and implementations should abort any in-flight I/O when it is.
+//
+// A Command run with a timeout (see Check.Timeout) is given a snapshot of the
+// Check rather than the Check itself, since the Check may execute again while
+// an abandoned Command is still running.
+type ContextCommand interface {
+	Command
+	RunContext(ctx context.Context, chk *Check) (*Result, error)
+}
+
+// CommandWithContext returns cmd as a ContextCommand.  If cmd does not
+// implement ContextCommand itself, it is wrapped in an adapter that refuses
+// to run once ctx is done but otherwise just calls cmd.Run().
+func CommandWithContext(cmd Command) ContextCommand {
+	if cc, ok := cmd.(ContextCommand); ok {
+		return cc
+	}
+	return commandAdapter{cmd}
+}
+
+type commandAdapter struct {
+	Command
+}
+
+func (a commandAdapter) RunContext(ctx context.Context, chk *Check) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return MakeUnknownResult("CMD_FAILURE"), err
+	}
+	return a.Run(chk)
+}
+
 // Handler mutates and/or processes a Check after it has executed.  Mutate()
 // is called first and sequentially in the order defined in the Check.  This
 // allows the second mutation to see the first mutations, etc.  Process() is
@@ -282,6 +686,86 @@ type Handler interface {
 	Process(check *Check, newResult *Result, newIncident *Incident) error
 }
 
+// HandlerError is the error returned (wrapped) by Execute when a Handler's
+// Process() fails.
+type HandlerError struct {
+	Handler Handler
+	Err     error
+}
+
+func (e *HandlerError) Error() string {
+	t := reflect.TypeOf(e.Handler)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return fmt.Sprintf("error in handler '%s': %v", t.PkgPath()+"."+t.Name(), e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// HandlerErrors returns every HandlerError within an error returned by
+// Execute.
+func HandlerErrors(err error) []*HandlerError {
+	var errs []error
+	if me, ok := err.(*multierror.Error); ok {
+		errs = me.WrappedErrors()
+	} else if err != nil {
+		errs = []error{err}
+	}
+
+	var handlerErrs []*HandlerError
+	for _, e := range errs {
+		var he *HandlerError
+		if errors.As(e, &he) {
+			handlerErrs = append(handlerErrs, he)
+		}
+	}
+	return handlerErrs
+}
+
+// ResultMutator is a Handler that mutates a Result before the Check determines
+// its state type and Incident, such as a Handler that re-evaluates the
+// Result's state.  MutateResult() is called sequentially in the order the
+// Handlers are defined on the Check, before any Handler's Mutate().
+type ResultMutator interface {
+	Handler
+
+	MutateResult(check *Check, newResult *Result)
+}
+
+// ContextHandler is a Handler whose processing can be cancelled through a
+// context.Context.
+type ContextHandler interface {
+	Handler
+
+	// ProcessContext is like Process but should abort any in-flight I/O when
+	// ctx is done.
+	ProcessContext(ctx context.Context, check *Check, newResult *Result, newIncident *Incident) error
+}
+
+// HandlerWithContext returns h as a ContextHandler.  If h does not implement
+// ContextHandler itself, it is wrapped in an adapter that refuses to process
+// once ctx is done but otherwise just calls h.Process().
+func HandlerWithContext(h Handler) ContextHandler {
+	if ch, ok := h.(ContextHandler); ok {
+		return ch
+	}
+	return handlerAdapter{h}
+}
+
+type handlerAdapter struct {
+	Handler
+}
+
+func (a handlerAdapter) ProcessContext(ctx context.Context, check *Check, newResult *Result, newIncident *Incident) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Process(check, newResult, newIncident)
+}
+
 // Queue is used by a server.Server to feed it work (Checks to execute).
 type Queue interface {
 	Enqueue(chk *Check)
@@ -290,6 +774,21 @@ type Queue interface {
 	Flush()
 }
 
+// WaitingQueue is a Queue that can wait for its next Check to come due, so
+// that a server.Server need not poll it.
+type WaitingQueue interface {
+	Queue
+
+	// DequeueWait blocks until a Check is due and returns it, or returns nil
+	// once ctx is done.  Enqueueing a Check that is due sooner than the one
+	// being waited on must wake it.
+	DequeueWait(ctx context.Context) *Check
+
+	// NextDueAt returns when the next Check in the queue is due, or false if
+	// the queue is empty.
+	NextDueAt() (time.Time, bool)
+}
+
 // Schedule is used by a Check to provide its execution schedule.
 type Schedule interface {
 	// DueAt returns a time.Time of the exact point in time the Check will
@@ -299,12 +798,12 @@ type Schedule interface {
 
 // PeriodicSchedule is a simple Scheduler that is due every IntervalSeconds seconds
 type PeriodicSchedule struct {
-	IntervalSeconds int
+	IntervalSeconds int `json:"intervalSeconds"`
 }
 
 func (s PeriodicSchedule) DueAt(check *Check) time.Time {
 	if check.LastCheck == nil {
-		return time.Now()
+		return check.Environment().Now()
 	}
 
 	return check.LastCheck.Add(time.Duration(s.IntervalSeconds) * time.Second)
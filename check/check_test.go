@@ -1,6 +1,8 @@
 package check
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -78,3 +80,196 @@ func TestPeriodicSchedule_DueAt(t *testing.T) {
 		}
 	}
 }
+
+type testCommand struct {
+	ran bool
+}
+
+func (c *testCommand) Run(*Check) (*Result, error) {
+	c.ran = true
+	return NewResult(StateOk, "", nil), nil
+}
+
+type testContextCommand struct {
+	ctx context.Context
+}
+
+func (c *testContextCommand) Run(chk *Check) (*Result, error) {
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *testContextCommand) RunContext(ctx context.Context, _ *Check) (*Result, error) {
+	c.ctx = ctx
+	return NewResult(StateOk, "", nil), nil
+}
+
+func TestCheck_ExecuteContextPassesContextToCommand(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "foo")
+
+	cmd := &testContextCommand{}
+	c := &Check{Command: cmd}
+	if err := c.ExecuteContext(ctx); err != nil {
+		t.Fatalf("ExecuteContext(): unexpected error: %v", err)
+	}
+
+	if cmd.ctx == nil || cmd.ctx.Value(ctxKey{}) != "foo" {
+		t.Error("ExecuteContext(): context was not passed to the command")
+	}
+}
+
+func TestCommandWithContext_AdapterDoesNotRunWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cmd := &testCommand{}
+	result, err := CommandWithContext(cmd).RunContext(ctx, &Check{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RunContext(): expected context.Canceled error, got %v", err)
+	}
+	if result.State != StateUnknown {
+		t.Errorf("RunContext(): expected UNKNOWN result, got %v", result.State)
+	}
+	if cmd.ran {
+		t.Error("RunContext(): command ran despite context being done")
+	}
+
+	result, err = CommandWithContext(cmd).RunContext(context.Background(), &Check{})
+	if err != nil {
+		t.Errorf("RunContext(): unexpected error: %v", err)
+	}
+	if !cmd.ran {
+		t.Error("RunContext(): command did not run")
+	}
+}
+
+type blockingCommand struct {
+	unblock chan struct{}
+}
+
+func (c *blockingCommand) Run(*Check) (*Result, error) {
+	<-c.unblock
+	return NewResult(StateOk, "", nil), nil
+}
+
+type recordingHandler struct {
+	result *Result
+}
+
+func (h *recordingHandler) Mutate(*Check, *Result, *Incident) {}
+
+func (h *recordingHandler) Process(_ *Check, result *Result, _ *Incident) error {
+	h.result = result
+	return nil
+}
+
+func TestCheck_ExecuteAbandonsCommandAfterTimeout(t *testing.T) {
+	cmd := &blockingCommand{unblock: make(chan struct{})}
+	defer close(cmd.unblock)
+	handler := &recordingHandler{}
+	c := &Check{Command: cmd, Handlers: []Handler{handler}, Timeout: 10 * time.Millisecond}
+
+	err := c.Execute()
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Execute(): expected ErrTimeout, got %v", err)
+	}
+	if c.LastResult == nil || c.LastResult.State != StateUnknown || c.LastResult.ReasonCode != "TIMEOUT" {
+		t.Errorf("Execute(): expected UNKNOWN/TIMEOUT result, got %v", c.LastResult)
+	}
+	if handler.result != c.LastResult {
+		t.Error("Execute(): handlers did not process the timed out result")
+	}
+}
+
+// lateReadingCommand blocks until unblocked, then reads the Check it was given
+// as a delta-computing Command would.
+type lateReadingCommand struct {
+	unblock chan struct{}
+	done    chan *Check
+}
+
+func (c *lateReadingCommand) Run(chk *Check) (*Result, error) {
+	<-c.unblock
+	_ = chk.LastResult
+	_ = chk.History.Results()
+	_ = chk.Meta["key"]
+	chk.Debugf("late read")
+	c.done <- chk
+	return NewResult(StateOk, "", nil), nil
+}
+
+func TestCheck_ExecuteGivesAbandonedCommandASnapshot(t *testing.T) {
+	cmd := &lateReadingCommand{unblock: make(chan struct{}), done: make(chan *Check, 1)}
+	c := &Check{
+		Id:      "check-1",
+		Command: cmd,
+		Meta:    map[string]any{"key": "value"},
+		Timeout: 10 * time.Millisecond,
+		History: NewResultHistory(5, 0),
+	}
+
+	if err := c.Execute(); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Execute(): expected ErrTimeout, got %v", err)
+	}
+
+	// the abandoned Command reads its Check while the Check executes again
+	close(cmd.unblock)
+	c.Command = &stateCommand{state: StateCrit}
+	c.Meta["key"] = "changed"
+	c.SetDebug(true)
+	_ = c.Execute()
+
+	snapshot := <-cmd.done
+	if snapshot == c || snapshot.Id != c.Id {
+		t.Errorf("RunContext(): expected a snapshot of check %s, got %p (check is %p)", c.Id, snapshot, c)
+	}
+	if snapshot.History.Len() != 0 {
+		t.Errorf("RunContext(): expected the snapshot's history to hold no results, got %d", snapshot.History.Len())
+	}
+}
+
+type stateCommand struct {
+	state ResultState
+}
+
+func (c *stateCommand) Run(*Check) (*Result, error) {
+	return NewResult(c.state, "", nil), nil
+}
+
+func TestCheck_ExecuteRetriesSoftStatesBeforeOpeningIncident(t *testing.T) {
+	cmd := &stateCommand{state: StateCrit}
+	c := &Check{Command: cmd, MaxAttempts: 3, RetryInterval: 5 * time.Second, Schedule: &PeriodicSchedule{IntervalSeconds: 60}}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		_ = c.Execute()
+		if c.StateType != StateTypeSoft || c.Attempt != attempt {
+			t.Fatalf("Execute(): expected SOFT attempt %d, got %s attempt %d", attempt, c.StateType, c.Attempt)
+		}
+		if c.Incident != nil {
+			t.Fatalf("Execute(): unexpected incident on soft attempt %d", attempt)
+		}
+		if want := c.LastCheck.Add(5 * time.Second); c.DueAt().Compare(want) != 0 {
+			t.Errorf("DueAt(): expected retry at %v, got %v", want, c.DueAt())
+		}
+	}
+
+	_ = c.Execute()
+	if c.StateType != StateTypeHard || c.Attempt != 3 {
+		t.Fatalf("Execute(): expected HARD attempt 3, got %s attempt %d", c.StateType, c.Attempt)
+	}
+	if c.StateCount != 3 {
+		t.Errorf("Execute(): expected state count 3, got %d", c.StateCount)
+	}
+	if c.Incident == nil || c.Incident.FromState != StateOk || c.Incident.ToState != StateCrit {
+		t.Errorf("Execute(): expected OK->CRIT incident, got %v", c.Incident)
+	}
+	if want := c.LastCheck.Add(60 * time.Second); c.DueAt().Compare(want) != 0 {
+		t.Errorf("DueAt(): expected %v once hard, got %v", want, c.DueAt())
+	}
+
+	cmd.state = StateOk
+	_ = c.Execute()
+	if c.Attempt != 0 || c.StateType != StateTypeHard {
+		t.Errorf("Execute(): expected attempts reset on OK, got %s attempt %d", c.StateType, c.Attempt)
+	}
+}
@@ -0,0 +1,172 @@
+// Package aggregate provides a Check command whose state is computed from the results of other Checks rather than by
+// probing a device.
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"github.com/seankndy/gopoller/check"
+)
+
+func init() {
+	check.RegisterCommand("aggregate", func() check.Command { return &Command{} })
+}
+
+// ResultResolver resolves a Check ID into the Check's last Result.  A
+// *check.DependencyGraph is a ResultResolver for the Checks registered with it.
+type ResultResolver interface {
+	LastResult(id string) *check.Result
+}
+
+// Rule is how many members must be in a Condition's MemberState for the
+// Condition to hold.
+type Rule string
+
+const (
+	// RuleAll holds when every member is in the state.
+	RuleAll Rule = "all"
+	// RuleAny holds when at least one member is in the state.
+	RuleAny Rule = "any"
+	// RuleAtLeast holds when at least Condition.Count members are in the state.
+	RuleAtLeast Rule = "at_least"
+	// RulePercent holds when at least Condition.Percent percent of the members
+	// are in the state.
+	RulePercent Rule = "percent"
+)
+
+// Condition produces State when its Rule holds for the members in MemberState.
+// A member in a worse state counts as well, so a WARN MemberState also counts
+// CRIT members.
+type Condition struct {
+	MemberState check.ResultState `json:"memberState"`
+	Rule        Rule              `json:"rule"`
+	Count       int               `json:"count,omitempty"`
+	Percent     float64           `json:"percent,omitempty"`
+
+	// State and ReasonCode are the Result's state and reason code when the
+	// Condition holds.
+	State      check.ResultState `json:"state"`
+	ReasonCode string            `json:"reasonCode,omitempty"`
+}
+
+// holds returns true if the Condition holds for the number of members
+// matching MemberState out of total.
+func (c Condition) holds(matching, total int) bool {
+	switch c.Rule {
+	case RuleAll:
+		return total > 0 && matching == total
+	case RuleAny:
+		return matching > 0
+	case RuleAtLeast:
+		return matching >= c.Count
+	case RulePercent:
+		return total > 0 && float64(matching)/float64(total)*100 >= c.Percent
+	default:
+		return false
+	}
+}
+
+// matches returns true if a member in state counts towards the Condition.
+func (c Condition) matches(state check.ResultState) bool {
+	return state == c.MemberState || c.MemberState == check.StateWarn && state == check.StateCrit
+}
+
+// Command is a check.Command that aggregates the last Results of its Members.
+// Members without a Result count as UNKNOWN.  The worst State of the
+// Conditions that hold becomes the Result's state, or OK if none hold.
+//
+// Each member's state is emitted as a "member_state" metric tagged with its
+// ID (0=OK, 1=WARN, 2=CRIT, 3=UNKNOWN), along with a count of the members in
+// each state.
+type Command struct {
+	resolver ResultResolver
+
+	Members    []string    `json:"members"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// NewCommand creates a Command aggregating the Checks with the given IDs.
+func NewCommand(members []string, conditions ...Condition) *Command {
+	return &Command{
+		Members:    members,
+		Conditions: conditions,
+	}
+}
+
+// SetResolver sets the resolver the Members' Results are looked up with.  When
+// it is not set, the Check's DependencyResolver is used.
+func (c *Command) SetResolver(resolver ResultResolver) {
+	c.resolver = resolver
+}
+
+func (c *Command) Run(chk *check.Check) (*check.Result, error) {
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *Command) RunContext(ctx context.Context, chk *check.Check) (*check.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return check.MakeUnknownResult("CMD_FAILURE"), err
+	}
+
+	var resolver ResultResolver
+	if c.resolver != nil {
+		resolver = c.resolver
+	} else if chk.DependencyResolver != nil {
+		resolver = chk.DependencyResolver
+	} else {
+		return check.MakeUnknownResult("CMD_FAILURE"), errors.New("aggregate command has no result resolver")
+	}
+
+	counts := make(map[check.ResultState]int)
+	states := make([]check.ResultState, len(c.Members))
+	var metrics []check.ResultMetric
+	for i, id := range c.Members {
+		states[i] = check.StateUnknown
+		if result := resolver.LastResult(id); result != nil {
+			states[i] = result.State
+		}
+		counts[states[i]]++
+
+		chk.Debugf("aggregate member %s is %s", id, states[i])
+		metrics = append(metrics, check.NewGaugeMetric("member_state", float64(states[i]), check.ResultMetricUnitNone).
+			WithTags(map[string]string{"member": id}))
+	}
+	for _, state := range []check.ResultState{check.StateOk, check.StateWarn, check.StateCrit, check.StateUnknown} {
+		metrics = append(metrics, check.NewGaugeMetric("members_"+stateLabel(state), float64(counts[state]), check.ResultMetricUnitCount))
+	}
+
+	resultState, resultReason := check.StateOk, ""
+	held := false
+	for _, cond := range c.Conditions {
+		matching := 0
+		for _, state := range states {
+			if cond.matches(state) {
+				matching++
+			}
+		}
+		if !cond.holds(matching, len(states)) {
+			continue
+		}
+
+		chk.Debugf("aggregate condition %s %s holds with %d of %d members", cond.Rule, cond.MemberState, matching, len(states))
+		if !held || cond.State.Overrides(resultState) {
+			resultState, resultReason = cond.State, cond.ReasonCode
+			held = true
+		}
+	}
+
+	return check.NewResult(resultState, resultReason, metrics), nil
+}
+
+func stateLabel(state check.ResultState) string {
+	switch state {
+	case check.StateOk:
+		return "ok"
+	case check.StateWarn:
+		return "warn"
+	case check.StateCrit:
+		return "crit"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,163 @@
+package aggregate
+
+import (
+	"github.com/seankndy/gopoller/check"
+	"reflect"
+	"testing"
+)
+
+type mapResolver map[string]check.ResultState
+
+func (r mapResolver) LastResult(id string) *check.Result {
+	state, ok := r[id]
+	if !ok {
+		return nil
+	}
+	return check.NewResult(state, "", nil)
+}
+
+func TestConditionsDetermineResultState(t *testing.T) {
+	uplinks := []string{"uplink1", "uplink2", "uplink3"}
+	twoOfThreeCrit := Condition{MemberState: check.StateCrit, Rule: RuleAtLeast, Count: 2, State: check.StateCrit, ReasonCode: "UPLINKS_DOWN"}
+	anyWarn := Condition{MemberState: check.StateWarn, Rule: RuleAny, State: check.StateWarn, ReasonCode: "UPLINK_DEGRADED"}
+	allUnknown := Condition{MemberState: check.StateUnknown, Rule: RuleAll, State: check.StateUnknown, ReasonCode: "NO_DATA"}
+	halfCrit := Condition{MemberState: check.StateCrit, Rule: RulePercent, Percent: 50, State: check.StateCrit, ReasonCode: "HALF_DOWN"}
+
+	tests := []struct {
+		name       string
+		members    mapResolver
+		conditions []Condition
+		wantState  check.ResultState
+		wantReason string
+	}{
+		{
+			name:       "all ok",
+			members:    mapResolver{"uplink1": check.StateOk, "uplink2": check.StateOk, "uplink3": check.StateOk},
+			conditions: []Condition{twoOfThreeCrit, anyWarn},
+			wantState:  check.StateOk,
+		},
+		{
+			name:       "one crit counts as warn",
+			members:    mapResolver{"uplink1": check.StateCrit, "uplink2": check.StateOk, "uplink3": check.StateOk},
+			conditions: []Condition{twoOfThreeCrit, anyWarn},
+			wantState:  check.StateWarn,
+			wantReason: "UPLINK_DEGRADED",
+		},
+		{
+			name:       "two crit",
+			members:    mapResolver{"uplink1": check.StateCrit, "uplink2": check.StateCrit, "uplink3": check.StateOk},
+			conditions: []Condition{anyWarn, twoOfThreeCrit},
+			wantState:  check.StateCrit,
+			wantReason: "UPLINKS_DOWN",
+		},
+		{
+			name:       "missing members are unknown",
+			members:    mapResolver{},
+			conditions: []Condition{allUnknown},
+			wantState:  check.StateUnknown,
+			wantReason: "NO_DATA",
+		},
+		{
+			name:       "percentage below threshold",
+			members:    mapResolver{"uplink1": check.StateCrit, "uplink2": check.StateOk, "uplink3": check.StateOk},
+			conditions: []Condition{halfCrit},
+			wantState:  check.StateOk,
+		},
+		{
+			name:       "percentage at threshold",
+			members:    mapResolver{"uplink1": check.StateCrit, "uplink2": check.StateCrit, "uplink3": check.StateOk},
+			conditions: []Condition{halfCrit},
+			wantState:  check.StateCrit,
+			wantReason: "HALF_DOWN",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewCommand(uplinks, tt.conditions...)
+			cmd.SetResolver(tt.members)
+
+			result, err := cmd.Run(&check.Check{})
+			if err != nil {
+				t.Fatalf("Run(): unexpected error: %v", err)
+			}
+			if result.State != tt.wantState || result.ReasonCode != tt.wantReason {
+				t.Errorf("Run(): expected %s/%s, got %s/%s", tt.wantState, tt.wantReason, result.State, result.ReasonCode)
+			}
+		})
+	}
+}
+
+func TestEmitsMemberStatesAsMetrics(t *testing.T) {
+	cmd := NewCommand([]string{"dns1", "dns2"})
+	cmd.SetResolver(mapResolver{"dns1": check.StateCrit})
+
+	result, _ := cmd.Run(&check.Check{})
+
+	want := map[string]string{"dns1": "2", "dns2": "3"}
+	for member, value := range want {
+		m := result.Metric("member_state", map[string]string{"member": member})
+		if m == nil || m.Value != value {
+			t.Errorf("Run(): expected member_state %s for %s, got %v", value, member, m)
+		}
+	}
+	for label, value := range map[string]string{"members_ok": "0", "members_crit": "1", "members_unknown": "1"} {
+		if m := result.Metric(label, nil); m == nil || m.Value != value {
+			t.Errorf("Run(): expected %s=%s, got %v", label, value, m)
+		}
+	}
+}
+
+func TestUsesChecksDependencyResolver(t *testing.T) {
+	graph := check.NewDependencyGraph()
+	member := check.New("member", check.WithCommand(&stateCommand{state: check.StateCrit}))
+	_ = graph.Register(member)
+	_ = member.Execute()
+
+	chk := check.New("aggregate",
+		check.WithCommand(NewCommand([]string{"member"}, Condition{MemberState: check.StateCrit, Rule: RuleAll, State: check.StateCrit})),
+		check.WithDependencyResolver(graph),
+	)
+	_ = chk.Execute()
+
+	if chk.LastResult.State != check.StateCrit {
+		t.Errorf("Execute(): expected CRIT from member's result, got %s", chk.LastResult.State)
+	}
+}
+
+func TestReturnsUnknownWithoutResolver(t *testing.T) {
+	result, err := NewCommand([]string{"member"}).Run(&check.Check{})
+
+	if err == nil || result.State != check.StateUnknown {
+		t.Errorf("Run(): expected UNKNOWN result and error, got %s (%v)", result.State, err)
+	}
+}
+
+type stateCommand struct {
+	state check.ResultState
+}
+
+func (c *stateCommand) Run(*check.Check) (*check.Result, error) {
+	return check.NewResult(c.state, "", nil), nil
+}
+
+func TestCommandEncodesConditions(t *testing.T) {
+	cmd := NewCommand([]string{"uplink1"}, Condition{MemberState: check.StateCrit, Rule: RuleAny, State: check.StateWarn, ReasonCode: "UPLINK_DOWN"})
+
+	data, err := check.MarshalCommand(cmd)
+	if err != nil {
+		t.Fatalf("MarshalCommand(): unexpected error: %v", err)
+	}
+	want := `{"type":"aggregate","config":{"members":["uplink1"],"conditions":[{"memberState":"CRIT","rule":"any","state":"WARN","reasonCode":"UPLINK_DOWN"}]}}`
+	if string(data) != want {
+		t.Errorf("MarshalCommand() = %s, want %s", data, want)
+	}
+
+	decoded, err := check.UnmarshalCommand(data)
+	if err != nil {
+		t.Fatalf("UnmarshalCommand(): unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, cmd) {
+		t.Errorf("UnmarshalCommand() = %+v, want %+v", decoded, cmd)
+	}
+}
@@ -1,12 +1,18 @@
 package ciscoresources
 
 import (
+	"context"
 	"fmt"
 	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
 	"github.com/seankndy/gopoller/snmp"
 	"math/big"
 )
 
+func init() {
+	check.RegisterCommand("ciscoresources", func() check.Command { return &Command{} })
+}
+
 const (
 	OidCpu     = ".1.3.6.1.4.1.9.2.1.57.0"
 	OidMemFree = ".1.3.6.1.4.1.9.9.48.1.1.1.6.1"
@@ -21,6 +27,11 @@ type Command struct {
 	PercentCpuCritThreshold    int64
 	PercentMemoryWarnThreshold int64
 	PercentMemoryCritThreshold int64
+
+	// PercentCpuThreshold and PercentMemoryThreshold are threshold ranges
+	// for the CPU and memory utilization percentages.
+	PercentCpuThreshold    *threshold.Threshold
+	PercentMemoryThreshold *threshold.Threshold
 }
 
 func NewCommand(addr, community string, percCpuWarnThreshold, percCpuCritThreshold, percMemWarnThreshold, percMemCritThreshold int64) *Command {
@@ -38,14 +49,18 @@ func (c *Command) SetGetter(getter snmp.Getter) {
 }
 
 func (c *Command) Run(chk *check.Check) (*check.Result, error) {
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *Command) RunContext(ctx context.Context, chk *check.Check) (*check.Result, error) {
 	var getter snmp.Getter
 	if c.getter == nil {
-		getter = snmp.DefaultGetter
+		getter = snmp.EnvironmentGetter(chk.Environment())
 	} else {
 		getter = c.getter
 	}
 
-	objects, err := getter.Get(&c.Host, []string{OidCpu, OidMemUsed, OidMemFree})
+	objects, err := snmp.GetContext(ctx, getter, &c.Host, []string{OidCpu, OidMemUsed, OidMemFree})
 	if err != nil {
 		return check.MakeUnknownResult("CMD_FAILURE"), err
 	}
@@ -78,18 +93,15 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 
 	var resultState check.ResultState
 	var resultReasonCode string
-	resultMetrics := []check.ResultMetric{
-		{
-			Label: "cpu",
-			Value: cpuPerc.String(),
-			Type:  check.ResultMetricGauge,
-		},
-		{
-			Label: "memory",
-			Value: memoryPerc.String(),
-			Type:  check.ResultMetricGauge,
-		},
+	cpuMetric, err := check.ParseMetric("cpu", cpuPerc.String(), check.ResultMetricGauge, check.ResultMetricUnitPercent)
+	if err != nil {
+		return check.MakeUnknownResult("CMD_FAILURE"), err
 	}
+	memoryMetric, err := check.ParseMetric("memory", memoryPerc.String(), check.ResultMetricGauge, check.ResultMetricUnitPercent)
+	if err != nil {
+		return check.MakeUnknownResult("CMD_FAILURE"), err
+	}
+	resultMetrics := []check.ResultMetric{cpuMetric, memoryMetric}
 
 	if cpuPerc.Cmp(big.NewInt(c.PercentCpuCritThreshold)) > 0 {
 		resultState = check.StateCrit
@@ -107,5 +119,14 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 		resultState = check.StateOk
 	}
 
+	cpuPercFloat, _ := new(big.Float).SetInt(cpuPerc).Float64()
+	memoryPercFloat, _ := new(big.Float).SetInt(memoryPerc).Float64()
+	if s, r := threshold.Evaluate(c.PercentCpuThreshold, cpuPercFloat, "CPU_USAGE_HIGH"); s.Overrides(resultState) {
+		resultState, resultReasonCode = s, r
+	}
+	if s, r := threshold.Evaluate(c.PercentMemoryThreshold, memoryPercFloat, "MEM_USAGE_HIGH"); s.Overrides(resultState) {
+		resultState, resultReasonCode = s, r
+	}
+
 	return check.NewResult(resultState, resultReasonCode, resultMetrics), nil
 }
@@ -5,10 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
 	"net"
+	"strconv"
 	"time"
 )
 
+func init() {
+	check.RegisterCommand("dns", func() check.Command { return &Command{} })
+}
+
 type QueryType string
 
 const (
@@ -30,37 +36,49 @@ type Command struct {
 
 	WarnRespTimeThreshold time.Duration
 	CritRespTimeThreshold time.Duration
+
+	// RespTimeThreshold is a threshold range for the response time in
+	// milliseconds.
+	RespTimeThreshold *threshold.Threshold
 }
 
 func (c *Command) Run(chk *check.Check) (*check.Result, error) {
-	r := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: c.ServerTimeout,
-			}
-			return d.DialContext(ctx, network, fmt.Sprintf("%s:%d", c.ServerIp, c.ServerPort))
-		},
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *Command) RunContext(ctx context.Context, chk *check.Check) (*check.Result, error) {
+	env := chk.Environment()
+	server := net.JoinHostPort(c.ServerIp, strconv.Itoa(int(c.ServerPort)))
+	var r check.Resolver
+	if env.NewResolver != nil {
+		r = env.NewResolver(server)
+	} else {
+		r = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return env.DialContext(ctx, network, server, c.ServerTimeout)
+			},
+		}
 	}
 
 	var resolvedEntries []string
 	var err error
-	startTime := time.Now()
+	startTime := env.Now()
 	switch c.QueryType {
 	case Host:
 		chk.Debugf("sending Host request of %s to %s:%d", c.Query, c.ServerIp, c.ServerPort)
-		resolvedEntries, err = r.LookupHost(context.Background(), c.Query)
+		resolvedEntries, err = r.LookupHost(ctx, c.Query)
 	case CNAME:
 		chk.Debugf("sending CNAME request of %s to %s:%d", c.Query, c.ServerIp, c.ServerPort)
 		var name string
-		name, err = r.LookupCNAME(context.Background(), c.Query)
+		name, err = r.LookupCNAME(ctx, c.Query)
 		if err != nil {
 			resolvedEntries = append(resolvedEntries, name)
 		}
 	case MX:
 		chk.Debugf("sending MX request of %s to %s:%d", c.Query, c.ServerIp, c.ServerPort)
 		var records []*net.MX
-		records, err = r.LookupMX(context.Background(), c.Query)
+		records, err = r.LookupMX(ctx, c.Query)
 		if err != nil {
 			for _, mx := range records {
 				resolvedEntries = append(resolvedEntries, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
@@ -68,15 +86,15 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 		}
 	case TXT:
 		chk.Debugf("sending TXT request of %s to %s:%d", c.Query, c.ServerIp, c.ServerPort)
-		resolvedEntries, err = r.LookupTXT(context.Background(), c.Query)
+		resolvedEntries, err = r.LookupTXT(ctx, c.Query)
 	case PTR:
 		chk.Debugf("sending PTR request of %s to %s:%d", c.Query, c.ServerIp, c.ServerPort)
-		resolvedEntries, err = r.LookupAddr(context.Background(), c.Query)
+		resolvedEntries, err = r.LookupAddr(ctx, c.Query)
 	}
 
 	if err != nil {
 		var dnsErr *net.DNSError
-		if errors.As(err, &dnsErr) {
+		if ctx.Err() == nil && errors.As(err, &dnsErr) {
 			if dnsErr.Timeout() {
 				return check.NewResult(check.StateCrit, "CONNECTION_TIMEOUT", nil), nil
 			}
@@ -85,17 +103,13 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 		return check.NewResult(check.StateUnknown, "CMD_FAILURE", nil), err
 	}
 
-	respTime := time.Now().Sub(startTime)
+	respTime := env.Now().Sub(startTime)
 	respMs := float64(respTime.Microseconds()) / float64(time.Microsecond)
 
 	chk.Debugf("resp=%.3f", respMs)
 
 	resultMetrics := []check.ResultMetric{
-		{
-			Label: "resp",
-			Value: fmt.Sprintf("%.3f", respMs),
-			Type:  check.ResultMetricGauge,
-		},
+		check.NewGaugeMetric("resp", respMs, check.ResultMetricUnitMilliseconds).WithPrecision(3),
 	}
 	var resultState check.ResultState
 	var resultReasonCode string
@@ -130,6 +144,9 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 		resultState = check.StateOk
 		resultReasonCode = ""
 	}
+	if s, r := threshold.Evaluate(c.RespTimeThreshold, respMs, "RESP_TIME_EXCEEDED"); s.Overrides(resultState) {
+		resultState, resultReasonCode = s, r
+	}
 
 	return check.NewResult(resultState, resultReasonCode, resultMetrics), nil
 }
@@ -0,0 +1,43 @@
+package dns
+
+import (
+	"context"
+	"github.com/seankndy/gopoller/check"
+	"net"
+	"testing"
+	"time"
+)
+
+type hostResolver struct {
+	check.Resolver
+	hosts []string
+}
+
+func (r hostResolver) LookupHost(context.Context, string) ([]string, error) {
+	return r.hosts, nil
+}
+
+func TestEnvironmentResolverQueriesConfiguredServer(t *testing.T) {
+	var servers []string
+	env := check.NewEnvironment(check.WithResolver(func(address string) check.Resolver {
+		servers = append(servers, address)
+		return hostResolver{hosts: []string{"192.0.2.1"}}
+	}))
+	cmd := &Command{
+		ServerIp:              "198.51.100.53",
+		ServerPort:            53,
+		Query:                 "example.com",
+		QueryType:             Host,
+		Expected:              &[]string{"192.0.2.1"},
+		WarnRespTimeThreshold: time.Minute,
+		CritRespTimeThreshold: time.Minute,
+	}
+
+	result, err := cmd.Run(check.New("check-1", check.WithEnvironment(env)))
+	if err != nil || result.State != check.StateOk {
+		t.Errorf("Run(): expected OK, got %s (%v)", result.State, err)
+	}
+	if want := net.JoinHostPort(cmd.ServerIp, "53"); len(servers) != 1 || servers[0] != want {
+		t.Errorf("Run(): expected a resolver for %s, got %v", want, servers)
+	}
+}
@@ -6,11 +6,20 @@ import (
 	"errors"
 	"fmt"
 	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
 	"net/http"
 	"strings"
 	"time"
 )
 
+// ErrUnsupportedTransport is returned when a Command that skips SSL verification is given an HTTP client whose
+// Transport is not an *http.Transport, as there is no way to disable verification through it.
+var ErrUnsupportedTransport = errors.New("cannot skip SSL verification with the HTTP client's transport")
+
+func init() {
+	check.RegisterCommand("http", func() check.Command { return &Command{} })
+}
+
 // Command is a check.Command that makes an HTTP request and verifies the response code while also measuring response
 // time.
 type Command struct {
@@ -23,33 +32,40 @@ type Command struct {
 	ExpectedResponseCode  int
 	WarnRespTimeThreshold time.Duration
 	CritRespTimeThreshold time.Duration
+
+	// RespTimeThreshold is a threshold range for the response time in
+	// milliseconds.
+	RespTimeThreshold *threshold.Threshold
 }
 
 func (c *Command) Run(chk *check.Check) (*check.Result, error) {
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.SkipSslVerify},
-		},
-		// don't follow redirects
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *Command) RunContext(ctx context.Context, chk *check.Check) (*check.Result, error) {
+	env := chk.Environment()
+	client, err := c.client(env)
+	if err != nil {
+		return check.MakeUnknownResult("CMD_FAILURE"), err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.ReqTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, c.ReqTimeout)
 	defer cancel()
-	request, err := http.NewRequestWithContext(ctx, c.ReqMethod, c.ReqUrl, strings.NewReader(c.ReqBody))
+	request, err := http.NewRequestWithContext(reqCtx, c.ReqMethod, c.ReqUrl, strings.NewReader(c.ReqBody))
 	if err != nil {
 		return check.MakeUnknownResult("CMD_FAILURE"), err
 	}
 
 	chk.Debugf("sending %s request to %s with body \"%s\"", c.ReqMethod, c.ReqUrl, c.ReqBody)
-	startTime := time.Now()
+	startTime := env.Now()
 	response, err := client.Do(request)
-	respTime := time.Now().Sub(startTime)
+	respTime := env.Now().Sub(startTime)
 	if err != nil {
 		var tlsVerifyErr *tls.CertificateVerificationError
-		if errors.Is(err, context.DeadlineExceeded) {
+		if ctx.Err() != nil {
+			// the caller gave up on us, so we cannot say anything about the endpoint
+			return check.MakeUnknownResult("CMD_FAILURE"), err
+		} else if errors.Is(err, context.DeadlineExceeded) {
 			return check.NewResult(check.StateCrit, "CONNECTION_ERROR", nil), err
 		} else if errors.As(err, &tlsVerifyErr) {
 			return check.NewResult(check.StateCrit, "HTTP_SSL_FAILURE", nil), err
@@ -63,11 +79,7 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 	chk.Debugf("resp=%.3f", respMs)
 
 	resultMetrics := []check.ResultMetric{
-		{
-			Label: "resp",
-			Value: fmt.Sprintf("%.3f", respMs),
-			Type:  check.ResultMetricGauge,
-		},
+		check.NewGaugeMetric("resp", respMs, check.ResultMetricUnitMilliseconds).WithPrecision(3),
 	}
 	var resultState check.ResultState
 	var resultReasonCode string
@@ -85,6 +97,50 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 		resultState = check.StateOk
 		resultReasonCode = ""
 	}
+	if s, r := threshold.Evaluate(c.RespTimeThreshold, respMs, "RESP_TIME_EXCEEDED"); s.Overrides(resultState) {
+		resultState, resultReasonCode = s, r
+	}
 
 	return check.NewResult(resultState, resultReasonCode, resultMetrics), nil
 }
+
+// client returns the HTTP client requests are made with.  The Environment's
+// HTTPClient is used when it has one (with a copy of its Transport that skips
+// SSL verification if the Command does), otherwise a client dialing with the
+// Environment's Dialer is created.  Either way, redirects are not followed.
+func (c *Command) client(env *check.Environment) (*http.Client, error) {
+	var client http.Client
+	if env.HTTPClient != nil {
+		client = *env.HTTPClient
+		if c.SkipSslVerify {
+			rt := client.Transport
+			if rt == nil {
+				rt = http.DefaultTransport
+			}
+			t, ok := rt.(*http.Transport)
+			if !ok {
+				return nil, fmt.Errorf("%w: %T", ErrUnsupportedTransport, rt)
+			}
+			t = t.Clone()
+			if t.TLSClientConfig == nil {
+				t.TLSClientConfig = &tls.Config{}
+			}
+			t.TLSClientConfig.InsecureSkipVerify = true
+			client.Transport = t
+		}
+	} else {
+		transport := &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.SkipSslVerify},
+		}
+		if env.Dialer != nil {
+			transport.DialContext = env.Dialer.DialContext
+		}
+		client.Transport = transport
+	}
+
+	// don't follow redirects
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &client, nil
+}
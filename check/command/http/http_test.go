@@ -0,0 +1,42 @@
+package http
+
+import (
+	"errors"
+	"github.com/seankndy/gopoller/check"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type wrappingTransport struct {
+	http.RoundTripper
+}
+
+func TestSkipSslVerifyWithEnvironmentClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cmd := &Command{
+		ReqUrl:                server.URL,
+		ReqMethod:             http.MethodGet,
+		ReqTimeout:            5 * time.Second,
+		SkipSslVerify:         true,
+		ExpectedResponseCode:  http.StatusOK,
+		WarnRespTimeThreshold: time.Minute,
+		CritRespTimeThreshold: time.Minute,
+	}
+
+	// a nil Transport is http.DefaultTransport, which verifies the server's self-signed certificate
+	chk := check.New("check-1", check.WithEnvironment(check.NewEnvironment(check.WithHTTPClient(&http.Client{}))))
+	result, err := cmd.Run(chk)
+	if err != nil || result.State != check.StateOk {
+		t.Errorf("Run(): expected OK without verifying SSL, got %s (%v)", result.State, err)
+	}
+
+	chk.SetEnvironment(check.NewEnvironment(check.WithHTTPClient(&http.Client{Transport: wrappingTransport{http.DefaultTransport}})))
+	result, err = cmd.Run(chk)
+	if !errors.Is(err, ErrUnsupportedTransport) || result.State != check.StateUnknown {
+		t.Errorf("Run(): expected UNKNOWN with ErrUnsupportedTransport, got %s (%v)", result.State, err)
+	}
+}
@@ -2,13 +2,19 @@
 package junsubpool
 
 import (
+	"context"
 	"fmt"
 	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
 	"github.com/seankndy/gopoller/snmp"
 	"math/big"
 	"strings"
 )
 
+func init() {
+	check.RegisterCommand("junsubpool", func() check.Command { return &Command{} })
+}
+
 const (
 	OidPoolAddrTotal  = ".1.3.6.1.4.1.2636.3.51.1.1.4.1.1.1.10"
 	OidPoolAddrsInUse = ".1.3.6.1.4.1.2636.3.51.1.1.4.1.1.1.11"
@@ -21,6 +27,10 @@ type Command struct {
 	IpPoolSnmpIndexes               []int
 	PercentUtilizationWarnThreshold float64
 	PercentUtilizationCritThreshold float64
+
+	// PercentUtilizationThreshold is a threshold range for the pool
+	// utilization percentage.
+	PercentUtilizationThreshold *threshold.Threshold
 }
 
 func NewCommand(addr, community string, ipPoolIndexes []int, percWarnThreshold, percCritThreshold float64) *Command {
@@ -37,14 +47,18 @@ func (c *Command) SetGetter(getter snmp.Getter) {
 }
 
 func (c *Command) Run(chk *check.Check) (*check.Result, error) {
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *Command) RunContext(ctx context.Context, chk *check.Check) (*check.Result, error) {
 	var getter snmp.Getter
 	if c.getter == nil {
-		getter = snmp.DefaultGetter
+		getter = snmp.EnvironmentGetter(chk.Environment())
 	} else {
 		getter = c.getter
 	}
 
-	objects, err := getter.Get(&c.Host, c.getOids())
+	objects, err := snmp.GetContext(ctx, getter, &c.Host, c.getOids())
 	if err != nil {
 		return check.MakeUnknownResult("CMD_FAILURE"), err
 	}
@@ -71,13 +85,11 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 
 	var resultState check.ResultState
 	var resultReasonCode string
-	resultMetrics := []check.ResultMetric{
-		{
-			Label: "total_pool_usage",
-			Value: fmt.Sprintf("%d", used),
-			Type:  check.ResultMetricGauge,
-		},
+	usage, err := check.ParseMetric("total_pool_usage", used.String(), check.ResultMetricGauge, check.ResultMetricUnitCount)
+	if err != nil {
+		return check.MakeUnknownResult("CMD_FAILURE"), err
 	}
+	resultMetrics := []check.ResultMetric{usage}
 
 	if percentUsed.Cmp(big.NewFloat(c.PercentUtilizationCritThreshold)) > 0 {
 		resultState = check.StateCrit
@@ -89,6 +101,11 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 		resultState = check.StateOk
 	}
 
+	percentUsedFloat, _ := percentUsed.Float64()
+	if s, r := threshold.Evaluate(c.PercentUtilizationThreshold, percentUsedFloat, "IP_POOL_USAGE_HIGH"); s.Overrides(resultState) {
+		resultState, resultReasonCode = s, r
+	}
+
 	return check.NewResult(resultState, resultReasonCode, resultMetrics), nil
 }
 
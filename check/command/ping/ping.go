@@ -1,15 +1,27 @@
 package ping
 
 import (
-	"fmt"
+	"context"
 	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
 	"time"
 )
 
+func init() {
+	check.RegisterCommand("ping", func() check.Command { return &Command{} })
+}
+
 type Pinger interface {
 	Run(*Command) (*PingerStats, error)
 }
 
+// ContextPinger is a Pinger that stops pinging when its context.Context is
+// done.
+type ContextPinger interface {
+	Pinger
+	RunContext(context.Context, *Command) (*PingerStats, error)
+}
+
 type PingerStats struct {
 	// PacketLoss is the percentage of packets lost
 	PacketLoss float64
@@ -36,6 +48,13 @@ type Command struct {
 	AvgRttCritThreshold     time.Duration
 	StdDevRttWarnThreshold  time.Duration
 	StdDevRttCritThreshold  time.Duration
+
+	// PacketLossThreshold, AvgRttThreshold and StdDevRttThreshold are
+	// threshold ranges for the packet loss percentage and the average and
+	// standard deviation of round-trip times in milliseconds.
+	PacketLossThreshold *threshold.Threshold
+	AvgRttThreshold     *threshold.Threshold
+	StdDevRttThreshold  *threshold.Threshold
 }
 
 func (c *Command) SetPinger(pinger Pinger) {
@@ -43,22 +62,47 @@ func (c *Command) SetPinger(pinger Pinger) {
 }
 
 var (
+	// DefaultPinger is the Pinger used when neither a Command nor its Check's
+	// check.Environment provides one.
+	//
+	// Deprecated: provide a Pinger with WithPinger instead.
 	DefaultPinger = &ProBingPinger{}
 )
 
+// WithPinger provides pinger to the ping Commands run in a check.Environment.
+func WithPinger(pinger Pinger) check.EnvOption {
+	return check.WithDependency[Pinger](pinger)
+}
+
 func (c *Command) Run(chk *check.Check) (*check.Result, error) {
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *Command) RunContext(ctx context.Context, chk *check.Check) (*check.Result, error) {
 	var pinger Pinger
 	if c.pinger != nil {
 		pinger = c.pinger
+	} else if p, ok := check.Dependency[Pinger](chk.Environment()); ok {
+		pinger = p
 	} else {
 		pinger = DefaultPinger
 	}
 
 	chk.Debugf("sending %d pings to %s", c.Count, c.Addr)
-	stats, err := pinger.Run(c)
+	var stats *PingerStats
+	var err error
+	if ctxPinger, ok := pinger.(ContextPinger); ok {
+		stats, err = ctxPinger.RunContext(ctx, c)
+	} else {
+		stats, err = pinger.Run(c)
+	}
 	if err != nil {
 		return check.MakeUnknownResult("CMD_FAILURE"), err
 	}
+	// a cancelled ping run returns partial statistics which would look like packet loss
+	if err = ctx.Err(); err != nil {
+		return check.MakeUnknownResult("CMD_FAILURE"), err
+	}
 
 	avgMs := float64(stats.AvgRtt.Microseconds()) / float64(time.Microsecond)
 	jitterMs := float64(stats.StdDevRtt.Microseconds()) / float64(time.Microsecond)
@@ -93,9 +137,23 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 		state = check.StateOk
 	}
 
+	for _, t := range []struct {
+		threshold         *threshold.Threshold
+		value             float64
+		defaultReasonCode string
+	}{
+		{c.PacketLossThreshold, lossPerc, "PKT_LOSS_HIGH"},
+		{c.AvgRttThreshold, avgMs, "LATENCY_HIGH"},
+		{c.StdDevRttThreshold, jitterMs, "JITTER_HIGH"},
+	} {
+		if s, r := threshold.Evaluate(t.threshold, t.value, t.defaultReasonCode); s.Overrides(state) {
+			state, reasonCode = s, r
+		}
+	}
+
 	return check.NewResult(state, reasonCode, []check.ResultMetric{
-		{Label: "avg", Value: fmt.Sprintf("%.2f", avgMs)},
-		{Label: "jitter", Value: fmt.Sprintf("%.2f", jitterMs)},
-		{Label: "loss", Value: fmt.Sprintf("%.2f", lossPerc)},
+		check.NewGaugeMetric("avg", avgMs, check.ResultMetricUnitMilliseconds).WithPrecision(2),
+		check.NewGaugeMetric("jitter", jitterMs, check.ResultMetricUnitMilliseconds).WithPrecision(2),
+		check.NewGaugeMetric("loss", lossPerc, check.ResultMetricUnitPercent).WithPrecision(2),
 	}), nil
 }
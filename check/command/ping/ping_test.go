@@ -3,6 +3,7 @@ package ping
 import (
 	"errors"
 	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
 	"github.com/stretchr/testify/mock"
 	"reflect"
 	"testing"
@@ -25,9 +26,9 @@ func TestResultMetricsReturnedProperly(t *testing.T) {
 	}
 
 	want := []check.ResultMetric{
-		{Label: "avg", Value: "23.45"},
-		{Label: "jitter", Value: "12.34"},
-		{Label: "loss", Value: "69.20"},
+		check.NewGaugeMetric("avg", 23.45, check.ResultMetricUnitMilliseconds).WithPrecision(2),
+		check.NewGaugeMetric("jitter", 12.34, check.ResultMetricUnitMilliseconds).WithPrecision(2),
+		check.NewGaugeMetric("loss", 69.2, check.ResultMetricUnitPercent).WithPrecision(2),
 	}
 	got := result.Metrics
 
@@ -108,6 +109,36 @@ func TestThresholdsTripStatesProperly(t *testing.T) {
 			wantResultState: check.StateCrit,
 			wantReasonCode:  "LATENCY_HIGH",
 		},
+		{
+			name: "avg_rtt_range_crit",
+			cmd: &Command{
+				PacketLossWarnThreshold: 3,
+				PacketLossCritThreshold: 5,
+				AvgRttThreshold:         threshold.MustNew("~:20", "@25:30", ""),
+			},
+			stats: &PingerStats{
+				PacketLoss: 0,
+				AvgRtt:     26 * time.Millisecond,
+				StdDevRtt:  5 * time.Millisecond,
+			},
+			wantResultState: check.StateCrit,
+			wantReasonCode:  "LATENCY_HIGH",
+		},
+		{
+			name: "jitter_range_warn_with_reason",
+			cmd: &Command{
+				PacketLossWarnThreshold: 3,
+				PacketLossCritThreshold: 5,
+				StdDevRttThreshold:      threshold.MustNew("2:4", "", "JITTER_OUT_OF_RANGE"),
+			},
+			stats: &PingerStats{
+				PacketLoss: 0,
+				AvgRtt:     19 * time.Millisecond,
+				StdDevRtt:  5 * time.Millisecond,
+			},
+			wantResultState: check.StateWarn,
+			wantReasonCode:  "JITTER_OUT_OF_RANGE",
+		},
 		{
 			name: "all_ok",
 			cmd: &Command{
@@ -178,3 +209,42 @@ func (m *MockPinger) Run(cmd *Command) (*PingerStats, error) {
 	args := m.Called(cmd)
 	return args.Get(0).(*PingerStats), args.Error(1)
 }
+
+func TestCommandRoundTripsThroughRegistry(t *testing.T) {
+	cmd := &Command{
+		Addr:                "10.0.0.1",
+		Count:               5,
+		Interval:            time.Second,
+		AvgRttWarnThreshold: 50 * time.Millisecond,
+		AvgRttThreshold:     threshold.MustNew("50", "100", "HIGH_RTT"),
+	}
+
+	data, err := check.MarshalCommand(cmd)
+	if err != nil {
+		t.Fatalf("MarshalCommand(): unexpected error: %v", err)
+	}
+	decoded, err := check.UnmarshalCommand(data)
+	if err != nil {
+		t.Fatalf("UnmarshalCommand(): unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(cmd, decoded) {
+		t.Errorf("round trip: expected %+v, got %+v", cmd, decoded)
+	}
+}
+
+func TestUsesPingerFromChecksEnvironment(t *testing.T) {
+	mockPinger := new(MockPinger)
+	mockPinger.On("Run", mock.Anything).Return(&PingerStats{PacketLoss: 100}, nil)
+	chk := check.New("check-1", check.WithEnvironment(check.NewEnvironment(WithPinger(mockPinger))))
+
+	result, err := (&Command{Addr: "192.0.2.1"}).Run(chk)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mockPinger.AssertExpectations(t)
+	if result.ReasonCode != "UNREACHABLE" {
+		t.Errorf("expected result from environment's pinger, got %s/%s", result.State, result.ReasonCode)
+	}
+}
@@ -1,6 +1,7 @@
 package ping
 
 import (
+	"context"
 	probing "github.com/prometheus-community/pro-bing"
 	"time"
 )
@@ -8,6 +9,10 @@ import (
 type ProBingPinger struct{}
 
 func (p *ProBingPinger) Run(cmd *Command) (*PingerStats, error) {
+	return p.RunContext(context.Background(), cmd)
+}
+
+func (p *ProBingPinger) RunContext(ctx context.Context, cmd *Command) (*PingerStats, error) {
 	pinger, err := probing.NewPinger(cmd.Addr)
 	if err != nil {
 		return nil, err
@@ -21,7 +26,7 @@ func (p *ProBingPinger) Run(cmd *Command) (*PingerStats, error) {
 		pinger.SetPrivileged(true)
 	}
 
-	err = pinger.Run()
+	err = pinger.RunWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
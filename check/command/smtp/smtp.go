@@ -1,19 +1,31 @@
 package smtp
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
 	"os"
 	"time"
 )
 
+func init() {
+	check.RegisterCommand("smtp", func() check.Command { return &Command{} })
+}
+
 type Client interface {
 	Connect(*Command) error
 	Close() error
 	Cmd(string) (int, time.Duration, error)
 }
 
+// ContextClient is a Client whose connection is aborted when the
+// context.Context passed to ConnectContext is done.
+type ContextClient interface {
+	Client
+	ConnectContext(context.Context, *Command) error
+}
+
 type NotReadyErr struct {
 	Cause error
 }
@@ -38,6 +50,10 @@ type Command struct {
 
 	WarnRespTimeThreshold time.Duration
 	CritRespTimeThreshold time.Duration
+
+	// RespTimeThreshold is a threshold range for the response time in
+	// milliseconds.
+	RespTimeThreshold *threshold.Threshold
 }
 
 func (c *Command) SetClient(client Client) {
@@ -45,19 +61,47 @@ func (c *Command) SetClient(client Client) {
 }
 
 var (
-	DefaultClient = &TextProtoSmtp{}
+	// DefaultClient, if set, is the Client used when neither a Command nor its
+	// Check's check.Environment provides one.  When it is not set, each run
+	// connects with a new TextProtoSmtp using the Check's check.Environment.
+	//
+	// Deprecated: provide a Client with WithClient instead.
+	DefaultClient Client
 )
 
-func (c *Command) Run(*check.Check) (result *check.Result, err error) {
+// WithClient provides client to the SMTP Commands run in a check.Environment.
+func WithClient(client Client) check.EnvOption {
+	return check.WithDependency[Client](client)
+}
+
+func (c *Command) Run(chk *check.Check) (*check.Result, error) {
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *Command) RunContext(ctx context.Context, chk *check.Check) (result *check.Result, err error) {
 	var client Client
 	if c.client != nil {
 		client = c.client
-	} else {
+	} else if cl, ok := check.Dependency[Client](chk.Environment()); ok {
+		client = cl
+	} else if DefaultClient != nil {
 		client = DefaultClient
+	} else {
+		// a TextProtoSmtp holds the state of one connection, so it cannot be shared between runs
+		client = &TextProtoSmtp{Env: chk.Environment()}
 	}
 
-	err = client.Connect(c)
+	if ctxClient, ok := client.(ContextClient); ok {
+		err = ctxClient.ConnectContext(ctx, c)
+	} else if err = ctx.Err(); err == nil {
+		err = client.Connect(c)
+	}
 	if err != nil {
+		if ctx.Err() != nil {
+			client.Close()
+			return check.MakeUnknownResult("CMD_FAILURE"), err
+		}
+
 		var notReadyErr *NotReadyErr
 		if errors.As(err, &notReadyErr) {
 			client.Close()
@@ -75,7 +119,7 @@ func (c *Command) Run(*check.Check) (result *check.Result, err error) {
 
 	actualResponseCode, respTime, err := client.Cmd(c.Send)
 	if err != nil {
-		if errors.Is(err, os.ErrDeadlineExceeded) {
+		if ctx.Err() == nil && errors.Is(err, os.ErrDeadlineExceeded) {
 			return check.NewResult(check.StateCrit, "CONNECTION_ERROR", nil), err
 		}
 		return check.MakeUnknownResult("CMD_FAILURE"), err
@@ -83,11 +127,7 @@ func (c *Command) Run(*check.Check) (result *check.Result, err error) {
 	respMs := float64(respTime.Microseconds()) / float64(time.Microsecond)
 
 	resultMetrics := []check.ResultMetric{
-		{
-			Label: "resp",
-			Value: fmt.Sprintf("%.3f", respMs),
-			Type:  check.ResultMetricGauge,
-		},
+		check.NewGaugeMetric("resp", respMs, check.ResultMetricUnitMilliseconds).WithPrecision(3),
 	}
 	var resultState check.ResultState
 	var resultReasonCode string
@@ -105,6 +145,9 @@ func (c *Command) Run(*check.Check) (result *check.Result, err error) {
 		resultState = check.StateOk
 		resultReasonCode = ""
 	}
+	if s, r := threshold.Evaluate(c.RespTimeThreshold, respMs, "RESP_TIME_EXCEEDED"); s.Overrides(resultState) {
+		resultState, resultReasonCode = s, r
+	}
 
 	return check.NewResult(resultState, resultReasonCode, resultMetrics), nil
 }
@@ -1,14 +1,66 @@
 package smtp
 
 import (
+	"bufio"
 	"errors"
 	"github.com/seankndy/gopoller/check"
 	"github.com/stretchr/testify/mock"
+	"net"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
 
+// serveSmtp accepts connections on l, greeting each and answering every command with 250.
+func serveSmtp(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			_, _ = conn.Write([]byte("220 test.local ESMTP\r\n"))
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				_, _ = conn.Write([]byte("250 test.local\r\n"))
+			}
+		}()
+	}
+}
+
+func TestConcurrentRunsUseTheirOwnConnection(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen(): %v", err)
+	}
+	defer l.Close()
+	go serveSmtp(l)
+
+	addr := l.Addr().(*net.TCPAddr)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := &Command{
+				Addr:                  addr.IP.String(),
+				Port:                  uint16(addr.Port),
+				Timeout:               5 * time.Second,
+				Send:                  "HELO test.local",
+				ExpectedResponseCode:  250,
+				WarnRespTimeThreshold: time.Minute,
+				CritRespTimeThreshold: time.Minute,
+			}
+			if result, err := cmd.Run(&check.Check{}); err != nil || result.State != check.StateOk {
+				t.Errorf("Run(): expected OK, got %s (%v)", result.State, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestResultMetricsReturnedProperly(t *testing.T) {
 	mockClient := new(MockClient)
 	mockClient.On("Connect", mock.Anything).Return(nil)
@@ -26,11 +78,7 @@ func TestResultMetricsReturnedProperly(t *testing.T) {
 	}
 
 	want := []check.ResultMetric{
-		{
-			Label: "resp",
-			Value: "123.451",
-			Type:  check.ResultMetricGauge,
-		},
+		check.NewGaugeMetric("resp", 123.451, check.ResultMetricUnitMilliseconds).WithPrecision(3),
 	}
 	got := result.Metrics
 
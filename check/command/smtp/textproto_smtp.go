@@ -1,26 +1,47 @@
 package smtp
 
 import (
-	"fmt"
+	"context"
+	"github.com/seankndy/gopoller/check"
 	"net"
 	"net/textproto"
+	"strconv"
 	"time"
 )
 
 type TextProtoSmtp struct {
+	// Env, when set, provides the Dialer connections are made with and the
+	// Clock response times are measured with.
+	Env *check.Environment
+
 	text *textproto.Conn
+
+	// stopCtxWatch stops the goroutine that aborts the connection's I/O when the context passed to ConnectContext
+	// is done
+	stopCtxWatch func() bool
 }
 
 func (t *TextProtoSmtp) Connect(c *Command) error {
-	dialer := net.Dialer{Timeout: c.Timeout}
-	conn, err := dialer.Dial("tcp", fmt.Sprintf("%s:%d", c.Addr, c.Port))
+	return t.ConnectContext(context.Background(), c)
+}
+
+func (t *TextProtoSmtp) ConnectContext(ctx context.Context, c *Command) error {
+	conn, err := t.Env.DialContext(ctx, "tcp", net.JoinHostPort(c.Addr, strconv.Itoa(int(c.Port))), c.Timeout)
 	if err != nil {
 		return err
 	}
-	err = conn.SetDeadline(time.Now().Add(c.Timeout))
+	deadline := time.Now().Add(c.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	err = conn.SetDeadline(deadline)
 	if err != nil {
 		return err
 	}
+	// expire the deadline immediately upon cancellation so any blocked reads/writes return
+	t.stopCtxWatch = context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
 
 	t.text = textproto.NewConn(conn)
 	_, _, err = t.text.ReadResponse(220)
@@ -32,6 +53,9 @@ func (t *TextProtoSmtp) Connect(c *Command) error {
 }
 
 func (t *TextProtoSmtp) Close() error {
+	if t.stopCtxWatch != nil {
+		t.stopCtxWatch()
+	}
 	if t.text != nil {
 		return t.text.Close()
 	}
@@ -39,7 +63,7 @@ func (t *TextProtoSmtp) Close() error {
 }
 
 func (t *TextProtoSmtp) Cmd(s string) (int, time.Duration, error) {
-	startTime := time.Now()
+	startTime := t.Env.Now()
 	id, err := t.text.Cmd(s)
 	if err != nil {
 		return 0, 0, err
@@ -50,7 +74,7 @@ func (t *TextProtoSmtp) Cmd(s string) (int, time.Duration, error) {
 
 	code, _, err := t.text.ReadResponse(-1)
 
-	duration := time.Now().Sub(startTime)
+	duration := t.Env.Now().Sub(startTime)
 
 	return code, duration, err
 }
@@ -1,14 +1,20 @@
 package snmp
 
 import (
+	"context"
 	"fmt"
 	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
 	"github.com/seankndy/gopoller/snmp"
 	"math/big"
 	"strings"
 	"time"
 )
 
+func init() {
+	check.RegisterCommand("snmp", func() check.Command { return &Command{} })
+}
+
 type OidMonitor struct {
 	Oid               string
 	Name              string
@@ -21,6 +27,13 @@ type OidMonitor struct {
 	CritMinReasonCode string
 	WarnMaxReasonCode string
 	CritMaxReasonCode string
+
+	// Threshold is a threshold range for the OID's value.
+	Threshold *threshold.Threshold
+
+	// Unit and Tags are set on the ResultMetric produced for the oid.
+	Unit check.ResultMetricUnit
+	Tags map[string]string
 }
 
 func NewOidMonitor(oid, name string) *OidMonitor {
@@ -32,23 +45,29 @@ func NewOidMonitor(oid, name string) *OidMonitor {
 }
 
 func (m OidMonitor) determineResultStateAndReasonFromResultValue(value *big.Float) (check.ResultState, string) {
+	state, reason := check.StateOk, ""
 	if m.CritMinReasonCode != "" && value.Cmp(big.NewFloat(m.CritMinThreshold)) < 0 {
-		return check.StateCrit, m.CritMinReasonCode
+		state, reason = check.StateCrit, m.CritMinReasonCode
 	} else if m.WarnMinReasonCode != "" && value.Cmp(big.NewFloat(m.WarnMinThreshold)) < 0 {
-		return check.StateWarn, m.WarnMinReasonCode
+		state, reason = check.StateWarn, m.WarnMinReasonCode
 	} else if m.CritMaxReasonCode != "" && value.Cmp(big.NewFloat(m.CritMaxThreshold)) > 0 {
-		return check.StateCrit, m.CritMaxReasonCode
+		state, reason = check.StateCrit, m.CritMaxReasonCode
 	} else if m.WarnMaxReasonCode != "" && value.Cmp(big.NewFloat(m.WarnMaxThreshold)) > 0 {
-		return check.StateWarn, m.WarnMaxReasonCode
+		state, reason = check.StateWarn, m.WarnMaxReasonCode
 	}
 
-	return check.StateOk, ""
+	f, _ := value.Float64()
+	if s, r := threshold.Evaluate(m.Threshold, f, "THRESHOLD_EXCEEDED"); s.Overrides(state) {
+		state, reason = s, r
+	}
+
+	return state, reason
 }
 
 func (m OidMonitor) String() string {
 	return fmt.Sprintf(
-		"name=%s oid=%s ppv=%f warn-min-thresh=%f crit-min-thres=%f warn-max-thres=%f crit-max-thres=%f warn-min-reason=%s crit-min-reason=%s warn-max-reason=%s crit-max-reason=%s",
-		m.Name, m.Oid, m.PostProcessValue, m.WarnMinThreshold, m.CritMinThreshold, m.WarnMaxThreshold, m.CritMaxThreshold, m.WarnMinReasonCode, m.CritMinReasonCode, m.WarnMaxReasonCode, m.CritMaxReasonCode,
+		"name=%s oid=%s ppv=%f warn-min-thresh=%f crit-min-thres=%f warn-max-thres=%f crit-max-thres=%f warn-min-reason=%s crit-min-reason=%s warn-max-reason=%s crit-max-reason=%s threshold=%v",
+		m.Name, m.Oid, m.PostProcessValue, m.WarnMinThreshold, m.CritMinThreshold, m.WarnMaxThreshold, m.CritMaxThreshold, m.WarnMinReasonCode, m.CritMinReasonCode, m.WarnMaxReasonCode, m.CritMaxReasonCode, m.Threshold,
 	)
 }
 
@@ -71,14 +90,18 @@ func NewCommand(addr, community string, monitors []OidMonitor) *Command {
 }
 
 func (c *Command) Run(chk *check.Check) (*check.Result, error) {
+	return c.RunContext(context.Background(), chk)
+}
+
+func (c *Command) RunContext(ctx context.Context, chk *check.Check) (*check.Result, error) {
 	var getter snmp.Getter
 	if c.getter == nil {
-		getter = snmp.DefaultGetter
+		getter = snmp.EnvironmentGetter(chk.Environment())
 	} else {
 		getter = c.getter
 	}
 
-	chk.Debugf("beginning snmp execution for check: %s", chk)
+	chk.Debugf("beginning snmp execution for check: %s", chk.Id)
 
 	// create a map of oid->oidMonitors for fast OidMonitor lookup when processing the result values below
 	oidMonitorsByOid := make(map[string]*OidMonitor, len(c.OidMonitors))
@@ -91,10 +114,10 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 		chk.Debugf("oid monitor: %s", c.OidMonitors[k])
 	}
 
-	currentTime := time.Now()
-	objects, err := getter.Get(&c.Host, rawOids)
+	currentTime := chk.Environment().Now()
+	objects, err := snmp.GetContext(ctx, getter, &c.Host, rawOids)
 	if err != nil {
-		if strings.Contains(err.Error(), "request timeout") {
+		if ctx.Err() == nil && strings.Contains(err.Error(), "request timeout") {
 			return check.MakeUnknownResult("CONNECTION_ERROR"), nil
 		}
 		return check.MakeUnknownResult("CMD_FAILURE"), err
@@ -141,9 +164,9 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 			// thresholds as it may be a worse state than what we are so far
 			if resultState != check.StateCrit {
 				// get last metric to calculate difference
-				lastMetric := getChecksLastResultMetricByLabel(chk, oidMonitor.Name)
+				lastMetric, lastTime := getChecksLastResultMetric(chk, oidMonitor.Name, oidMonitor.Tags)
 
-				chk.Debugf("counter oid %s last metric: %s", object.Oid, lastMetric)
+				chk.Debugf("counter oid %s last metric: %v", object.Oid, lastMetric)
 
 				var lastValue *big.Int
 				if lastMetric != nil {
@@ -151,7 +174,7 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 				}
 
 				// only if we have a last value can we calculate counter differences
-				if lastValue != nil && chk.LastCheck != nil {
+				if lastValue != nil && lastTime != nil && currentTime.Unix() > lastTime.Unix() {
 					// calculate the difference between previous and current result value, accounting for rollover
 					var diff *big.Int
 					if object.Type == snmp.Counter64 {
@@ -160,7 +183,7 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 						diff = snmp.CalculateCounterDiff(lastValue, value, 32)
 					}
 					timeDiff := new(big.Int).SetInt64(currentTime.Unix())
-					timeDiff.Sub(timeDiff, new(big.Int).SetInt64(chk.LastCheck.Unix()))
+					timeDiff.Sub(timeDiff, new(big.Int).SetInt64(lastTime.Unix()))
 					diff.Div(diff, timeDiff)
 
 					chk.Debugf("counter oid %s has difference value of %s", object.Oid, diff)
@@ -198,26 +221,31 @@ func (c *Command) Run(chk *check.Check) (*check.Result, error) {
 			resultMetricValue = value.Mul(value, big.NewFloat(oidMonitor.PostProcessValue)).Text('f', -1)
 		}
 
-		resultMetrics = append(resultMetrics, check.ResultMetric{
-			Label: oidMonitor.Name,
-			Value: resultMetricValue,
-			Type:  resultMetricType,
-		})
+		metric, err := check.ParseMetric(oidMonitor.Name, resultMetricValue, resultMetricType, oidMonitor.Unit)
+		if err != nil {
+			return check.MakeUnknownResult("CMD_FAILURE"), fmt.Errorf("snmp.Command.Run(): oid %s: %w", object.Oid, err)
+		}
+		metric.Tags = oidMonitor.Tags
+		resultMetrics = append(resultMetrics, metric)
 	}
 
 	return check.NewResult(resultState, resultReason, resultMetrics), nil
 }
 
-func getChecksLastResultMetricByLabel(chk *check.Check, label string) *check.ResultMetric {
-	if chk.LastResult != nil {
-		for k, _ := range chk.LastResult.Metrics {
-			if chk.LastResult.Metrics[k].Label == label {
-				return &chk.LastResult.Metrics[k]
-			}
+// getChecksLastResultMetric returns the most recent metric with the given label and tags along with the time it was
+// collected.  The Check's History is searched when it has one, so that a previous result missing the metric does not
+// prevent calculating counter differences.
+func getChecksLastResultMetric(chk *check.Check, label string, tags map[string]string) (*check.ResultMetric, *time.Time) {
+	if chk.History != nil {
+		if metric, t := chk.History.PreviousMetric(label, tags); metric != nil {
+			return metric, &t
 		}
 	}
+	if chk.LastResult != nil {
+		return chk.LastResult.Metric(label, tags), chk.LastCheck
+	}
 
-	return nil
+	return nil, nil
 }
 
 func convertBigIntToBigFloat(bigInt *big.Int) *big.Float {
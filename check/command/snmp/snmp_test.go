@@ -53,21 +53,9 @@ func TestReturnsResultWithMetricsFromSnmp(t *testing.T) {
 	result, _ := cmd.Run(&check.Check{})
 
 	assert.Equal(t, []check.ResultMetric{
-		{
-			Label: "foo1",
-			Value: "1234567",
-			Type:  check.ResultMetricGauge,
-		},
-		{
-			Label: "foo2",
-			Value: "7654321",
-			Type:  check.ResultMetricGauge,
-		},
-		{
-			Label: "foo3",
-			Value: "18237189237498",
-			Type:  check.ResultMetricCounter,
-		},
+		parseMetric(t, "foo1", "1234567", check.ResultMetricGauge),
+		parseMetric(t, "foo2", "7654321", check.ResultMetricGauge),
+		parseMetric(t, "foo3", "18237189237498", check.ResultMetricCounter),
 	}, result.Metrics)
 }
 
@@ -91,11 +79,7 @@ func TestPostProcessValuesAreAppliedToGauges(t *testing.T) {
 	cmd.SetGetter(getterMock)
 	result, _ := cmd.Run(&check.Check{})
 	assert.Equal(t, []check.ResultMetric{
-		{
-			Label: "foo1",
-			Value: "1234.567",
-			Type:  check.ResultMetricGauge,
-		},
+		parseMetric(t, "foo1", "1234.567", check.ResultMetricGauge),
 	}, result.Metrics)
 }
 
@@ -130,16 +114,8 @@ func TestPostProcessValuesAreNotAppliedToCounters(t *testing.T) {
 	result, _ := cmd.Run(&check.Check{})
 
 	assert.Equal(t, []check.ResultMetric{
-		{
-			Label: "foo1",
-			Value: "1234567",
-			Type:  check.ResultMetricCounter,
-		},
-		{
-			Label: "foo2",
-			Value: "1234567123131",
-			Type:  check.ResultMetricCounter,
-		},
+		parseMetric(t, "foo1", "1234567", check.ResultMetricCounter),
+		parseMetric(t, "foo2", "1234567123131", check.ResultMetricCounter),
 	}, result.Metrics)
 }
 
@@ -625,14 +601,19 @@ func TestStringGaugeValuesAreParsedAsFloats(t *testing.T) {
 	cmd.SetGetter(getterMock)
 	result, _ := cmd.Run(&check.Check{})
 	assert.Equal(t, []check.ResultMetric{
-		{
-			Label: "foo1",
-			Value: "0.12345678",
-			Type:  check.ResultMetricGauge,
-		},
+		parseMetric(t, "foo1", "0.12345678", check.ResultMetricGauge),
 	}, result.Metrics)
 }
 
+func parseMetric(t *testing.T, label, value string, metricType check.ResultMetricType) check.ResultMetric {
+	t.Helper()
+	m, err := check.ParseMetric(label, value, metricType, check.ResultMetricUnitNone)
+	if err != nil {
+		t.Fatalf("ParseMetric(): unexpected error: %v", err)
+	}
+	return m
+}
+
 type MockGetter struct {
 	mock.Mock
 }
@@ -0,0 +1,270 @@
+package check
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a Schedule that is due according to a standard cron
+// expression.  Both the 5-field (minute hour day-of-month month day-of-week)
+// and 6-field (second minute hour day-of-month month day-of-week) forms are
+// supported along with lists, ranges, steps, month and weekday names, and the
+// @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly macros.
+// As with cron, when both day-of-month and day-of-week are restricted, a day
+// matching either is due.
+//
+// The expression is evaluated in Location.  A CRON_TZ= or TZ= prefix on the
+// expression (ex. "CRON_TZ=America/Chicago 0 8-17 * * MON-FRI") overrides
+// the location passed to NewCronSchedule.
+type CronSchedule struct {
+	// Expression is the cron expression the schedule was created from.
+	Expression string
+
+	// Location is the time zone the expression is evaluated in.
+	Location *time.Location
+
+	// Since is the point in time after which a Check that has never executed
+	// is first due.  NewCronSchedule sets it to the current time.
+	Since time.Time
+
+	second, minute, hour, dom, month, dow uint64
+}
+
+type cronBounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronSecondBounds = cronBounds{min: 0, max: 59}
+	cronMinuteBounds = cronBounds{min: 0, max: 59}
+	cronHourBounds   = cronBounds{min: 0, max: 23}
+	cronDomBounds    = cronBounds{min: 1, max: 31}
+	cronMonthBounds  = cronBounds{min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	// day-of-week allows 7 as an alias for Sunday
+	cronDowBounds = cronBounds{min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// NewCronSchedule parses expr into a CronSchedule evaluated in loc (or
+// time.Local if loc is nil).
+func NewCronSchedule(expr string, loc *time.Location) (*CronSchedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	s := &CronSchedule{
+		Expression: expr,
+		Location:   loc,
+		Since:      time.Now(),
+	}
+
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		tz, rest, _ := strings.Cut(spec, " ")
+		_, tz, _ = strings.Cut(tz, "=")
+		var err error
+		if s.Location, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("invalid cron time zone %q: %w", tz, err)
+		}
+		spec = strings.TrimSpace(rest)
+	}
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 or 6 fields, got %d", expr, len(fields))
+	}
+
+	for i, f := range []struct {
+		dst    *uint64
+		bounds cronBounds
+	}{
+		{&s.second, cronSecondBounds},
+		{&s.minute, cronMinuteBounds},
+		{&s.hour, cronHourBounds},
+		{&s.dom, cronDomBounds},
+		{&s.month, cronMonthBounds},
+		{&s.dow, cronDowBounds},
+	} {
+		bits, err := parseCronField(fields[i], f.bounds)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		*f.dst = bits
+	}
+	// fold Sunday-as-7 into Sunday-as-0
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	return s, nil
+}
+
+// MustNewCronSchedule is like NewCronSchedule but panics if expr cannot be
+// parsed.
+func MustNewCronSchedule(expr string, loc *time.Location) *CronSchedule {
+	s, err := NewCronSchedule(expr, loc)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps into a bitset of the allowed values.
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		var lo, hi int
+		if rng == "*" || rng == "?" {
+			lo, hi = bounds.min, bounds.max
+		} else {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseCronValue(loStr, bounds); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(hiStr, bounds); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "a/n" means every n starting at a
+				hi = bounds.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(s string, bounds cronBounds) (int, error) {
+	if v, ok := bounds.names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < bounds.min || v > bounds.max {
+		return 0, fmt.Errorf("invalid value %q (must be %d-%d)", s, bounds.min, bounds.max)
+	}
+	return v, nil
+}
+
+// DueAt returns the first time matching the expression after the Check's
+// LastCheck, or after Since if the Check has never executed.
+func (s *CronSchedule) DueAt(check *Check) time.Time {
+	if check.LastCheck == nil {
+		return s.Next(s.Since)
+	}
+	return s.Next(*check.LastCheck)
+}
+
+// Next returns the first time matching the expression that is strictly after
+// t.  The zero time is returned if nothing matches within five years (ex.
+// "0 0 30 2 *").
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.Location)
+	// start at the next whole second
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<int(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.Location)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.Location)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	// hours, minutes and seconds advance in absolute time so that daylight saving transitions can't loop
+	for day := t.Day(); s.hour&(1<<t.Hour()) == 0; {
+		t = t.Add(time.Duration(59-t.Minute())*time.Minute + time.Duration(60-t.Second())*time.Second)
+		if t.Day() != day {
+			goto wrap
+		}
+	}
+
+	for hour := t.Hour(); s.minute&(1<<t.Minute()) == 0; {
+		t = t.Add(time.Duration(60-t.Second()) * time.Second)
+		if t.Hour() != hour {
+			goto wrap
+		}
+	}
+
+	for minute := t.Minute(); s.second&(1<<t.Second()) == 0; {
+		t = t.Add(time.Second)
+		if t.Minute() != minute {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<t.Day()) != 0
+	dowMatch := s.dow&(1<<int(t.Weekday())) != 0
+
+	// a restricted day-of-month and day-of-week are OR'd together, otherwise both must match
+	if s.dom != cronAll(cronDomBounds) && s.dow != cronAll(cronDowBounds)&^(1<<7) {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
+
+// cronAll returns the bitset of every value within bounds.
+func cronAll(bounds cronBounds) uint64 {
+	return (1<<(bounds.max+1) - 1) &^ (1<<bounds.min - 1)
+}
+
+func (s *CronSchedule) String() string {
+	return fmt.Sprintf("%s (%s)", s.Expression, s.Location)
+}
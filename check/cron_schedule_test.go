@@ -0,0 +1,128 @@
+package check
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			expr: "*/15 * * * *",
+			from: time.Date(2024, 3, 1, 10, 7, 30, 0, time.UTC),
+			want: time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC),
+		},
+		{ // strictly after
+			expr: "*/15 * * * *",
+			from: time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{ // 6-field with seconds
+			expr: "30 */5 * * * *",
+			from: time.Date(2024, 3, 1, 10, 7, 30, 0, time.UTC),
+			want: time.Date(2024, 3, 1, 10, 10, 30, 0, time.UTC),
+		},
+		{ // daily at 02:30 rolls over to the next day
+			expr: "30 2 * * *",
+			from: time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 3, 2, 2, 30, 0, 0, time.UTC),
+		},
+		{ // business hours, Friday evening goes to Monday morning
+			expr: "0 8-17 * * MON-FRI",
+			from: time.Date(2024, 3, 1, 17, 30, 0, 0, time.UTC), // Friday
+			want: time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC),
+		},
+		{ // Sunday as 7, month names, rolls over the year
+			expr: "0 0 * DEC 7",
+			from: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2025, 12, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{ // restricted day-of-month and day-of-week are OR'd
+			expr: "0 0 13 * FRI",
+			from: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), // Saturday
+			want: time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{ // macros
+			expr: "@yearly",
+			from: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{ // leap day
+			expr: "0 0 29 2 *",
+			from: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{ // impossible
+			expr: "0 0 30 2 *",
+			from: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Time{},
+		},
+		{ // time zone prefix, 02:30 does not exist on the spring forward day
+			expr: "CRON_TZ=America/Chicago 30 2 * * *",
+			from: time.Date(2024, 3, 9, 12, 0, 0, 0, chicago),
+			want: time.Date(2024, 3, 11, 2, 30, 0, 0, chicago),
+		},
+		{ // 01:30 happens twice on the fall back day, the first one is used
+			expr: "TZ=America/Chicago 30 1 * * *",
+			from: time.Date(2024, 11, 2, 12, 0, 0, 0, chicago),
+			want: time.Date(2024, 11, 3, 1, 30, 0, 0, chicago),
+		},
+	}
+
+	for _, tt := range tests {
+		s, err := NewCronSchedule(tt.expr, time.UTC)
+		if err != nil {
+			t.Fatalf("NewCronSchedule(%q): unexpected error: %v", tt.expr, err)
+		}
+		got := s.Next(tt.from)
+		if !got.Equal(tt.want) {
+			t.Errorf("Next(%q, %v) = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNewCronSchedule_InvalidExpressions(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * FOO *",
+		"TZ=Not/AZone * * * * *",
+	} {
+		if _, err := NewCronSchedule(expr, nil); err == nil {
+			t.Errorf("NewCronSchedule(%q): expected error, got nil", expr)
+		}
+	}
+}
+
+func TestCronSchedule_DueAt(t *testing.T) {
+	s := MustNewCronSchedule("0 * * * *", time.UTC)
+	s.Since = time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+
+	c := &Check{Schedule: s}
+	if want := time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC); !c.DueAt().Equal(want) {
+		t.Errorf("DueAt(): expected %v for never executed check, got %v", want, c.DueAt())
+	}
+
+	lastCheck := time.Date(2024, 3, 1, 11, 0, 2, 0, time.UTC)
+	c.LastCheck = &lastCheck
+	if want := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC); !c.DueAt().Equal(want) {
+		t.Errorf("DueAt(): expected %v, got %v", want, c.DueAt())
+	}
+}
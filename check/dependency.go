@@ -0,0 +1,124 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrDependencyCycle is returned (wrapped) by DependencyGraph.Register when
+// registering a Check would make it depend on itself.
+var ErrDependencyCycle = errors.New("check dependency cycle")
+
+// DependencyResolver resolves the IDs in a Check's DependsOn into the last
+// Results of those parent Checks.  Check.Execute() records every Result it
+// produces with the Check's DependencyResolver.
+type DependencyResolver interface {
+	// LastResult returns the last recorded Result of the Check with the given
+	// id, or nil if there is none.
+	LastResult(id string) *Result
+
+	// Record stores result as the last Result of chk.
+	Record(chk *Check, result *Result)
+}
+
+// DependencyGraph is an in-memory DependencyResolver that also validates the
+// dependencies between the Checks registered with it.  It is safe for
+// concurrent use.
+type DependencyGraph struct {
+	dependsOn   map[string][]string
+	lastResults map[string]*Result
+	mu          sync.RWMutex
+}
+
+func NewDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{
+		dependsOn:   make(map[string][]string),
+		lastResults: make(map[string]*Result),
+	}
+}
+
+// Register adds chk and its dependencies to the graph and sets the graph as
+// chk's DependencyResolver.  Parents do not have to be registered first, but
+// an error wrapping ErrDependencyCycle is returned (and chk is not
+// registered) if chk would end up depending on itself.  Registering a Check
+// with an already registered ID replaces its dependencies.
+func (g *DependencyGraph) Register(chk *Check) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if path := g.findPath(chk.DependsOn, chk.Id, nil); path != nil {
+		return fmt.Errorf("%w: %s -> %s", ErrDependencyCycle, chk.Id, strings.Join(path, " -> "))
+	}
+
+	g.dependsOn[chk.Id] = append([]string(nil), chk.DependsOn...)
+	if chk.LastResult != nil {
+		g.lastResults[chk.Id] = chk.LastResult
+	}
+	chk.DependencyResolver = g
+
+	return nil
+}
+
+// Unregister removes the Check with the given id from the graph.
+func (g *DependencyGraph) Unregister(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.dependsOn, id)
+	delete(g.lastResults, id)
+}
+
+// findPath returns the path of IDs leading from any of ids to target, or nil if there is none.
+func (g *DependencyGraph) findPath(ids []string, target string, visited map[string]bool) []string {
+	if visited == nil {
+		visited = make(map[string]bool)
+	}
+	for _, id := range ids {
+		if id == target {
+			return []string{id}
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		if path := g.findPath(g.dependsOn[id], target, visited); path != nil {
+			return append([]string{id}, path...)
+		}
+	}
+	return nil
+}
+
+func (g *DependencyGraph) LastResult(id string) *Result {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.lastResults[id]
+}
+
+func (g *DependencyGraph) Record(chk *Check, result *Result) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lastResults[chk.Id] = result
+}
+
+// unreachableVia returns the ID of the first parent Check that is down, or an empty string if all parents are up (or
+// unknown).  A parent is down when its last Result is a hard CRIT or when it is itself unreachable.
+func (c *Check) unreachableVia() string {
+	if c.DependencyResolver == nil {
+		return ""
+	}
+
+	for _, id := range c.DependsOn {
+		r := c.DependencyResolver.LastResult(id)
+		if r == nil {
+			continue
+		}
+		if (r.State == StateCrit && r.StateType == StateTypeHard) || r.UnreachableVia != "" {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package check
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDependencyGraph_RegisterDetectsCycles(t *testing.T) {
+	g := NewDependencyGraph()
+
+	if err := g.Register(New("a", WithDependsOn("b"))); err != nil {
+		t.Fatalf("Register(): unexpected error: %v", err)
+	}
+	if err := g.Register(New("b", WithDependsOn("c"))); err != nil {
+		t.Fatalf("Register(): unexpected error: %v", err)
+	}
+	if err := g.Register(New("c", WithDependsOn("a"))); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("Register(): expected ErrDependencyCycle for c -> a -> b -> c, got %v", err)
+	}
+	if err := g.Register(New("d", WithDependsOn("d"))); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("Register(): expected ErrDependencyCycle for self-dependency, got %v", err)
+	}
+	if err := g.Register(New("c", WithDependsOn("d"))); err != nil {
+		t.Errorf("Register(): unexpected error: %v", err)
+	}
+}
+
+func TestCheck_ExecuteSkipsCommandWhenParentIsDown(t *testing.T) {
+	g := NewDependencyGraph()
+	parentCmd := &stateCommand{state: StateCrit}
+	parent := New("router", WithCommand(parentCmd))
+	childCmd := &testCommand{}
+	child := New("switch", WithCommand(childCmd), WithDependsOn("router"))
+	grandchildCmd := &testCommand{}
+	grandchild := New("server", WithCommand(grandchildCmd), WithDependsOn("switch"))
+	for _, c := range []*Check{parent, child, grandchild} {
+		if err := g.Register(c); err != nil {
+			t.Fatalf("Register(): unexpected error: %v", err)
+		}
+	}
+
+	_ = parent.Execute()
+	_ = child.Execute()
+	_ = grandchild.Execute()
+
+	if childCmd.ran || grandchildCmd.ran {
+		t.Error("Execute(): command ran while a parent was down")
+	}
+	for _, c := range []*Check{child, grandchild} {
+		if c.LastResult.ReasonCode != "DEPENDENCY_UNREACHABLE" || c.LastResult.UnreachableVia == "" {
+			t.Errorf("Execute(): expected unreachable result, got %v", c.LastResult)
+		}
+		if c.Incident != nil {
+			t.Errorf("Execute(): unexpected incident %v on unreachable check", c.Incident)
+		}
+	}
+
+	parentCmd.state = StateOk
+	_ = parent.Execute()
+	_ = child.Execute()
+	if !childCmd.ran {
+		t.Error("Execute(): command did not run once parent recovered")
+	}
+}
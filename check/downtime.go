@@ -0,0 +1,117 @@
+package check
+
+import (
+	"reflect"
+	"slices"
+	"time"
+)
+
+// DowntimeHostMetaKey is the Check Meta key that Downtime.Host is matched
+// against.
+const DowntimeHostMetaKey = "host"
+
+// Downtime is a window of scheduled maintenance for one or more Checks.
+// Checks keep executing during downtime, but they do not open Incidents and
+// their Results are marked InDowntime.
+//
+// A Downtime is either one-off, from Start until End, or recurring, starting
+// at every occurrence of Recurrence and lasting Duration.  For a recurring
+// Downtime, a non-zero Start or End bounds when the recurrence applies.
+type Downtime struct {
+	// Id should be any unique value for this downtime.
+	Id string
+
+	// Comment describes why the downtime was scheduled.
+	Comment string
+
+	Start time.Time
+	End   time.Time
+
+	// Recurrence, when set, makes the downtime recurring.  For example,
+	// every Sunday from 02:00 to 04:00 Chicago time is a Recurrence of
+	// "CRON_TZ=America/Chicago 0 2 * * SUN" with a Duration of 2 hours.
+	Recurrence *CronSchedule
+	Duration   time.Duration
+
+	// CheckIds matches Checks by ID.
+	CheckIds []string
+
+	// Meta matches Checks whose Meta contains all of these key/values.
+	Meta map[string]any
+
+	// Host matches Checks whose Meta[DowntimeHostMetaKey] equals Host.
+	Host string
+}
+
+// NewRecurringDowntime creates a Downtime that starts at every occurrence of
+// the cron expression in loc and lasts duration.
+func NewRecurringDowntime(id, cronExpr string, loc *time.Location, duration time.Duration) (*Downtime, error) {
+	recurrence, err := NewCronSchedule(cronExpr, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &Downtime{
+		Id:         id,
+		Recurrence: recurrence,
+		Duration:   duration,
+	}, nil
+}
+
+// Matches returns true if the Downtime applies to chk, meaning chk matches
+// any of CheckIds, Meta or Host.  A Downtime without any of them applies to
+// nothing.
+func (d *Downtime) Matches(chk *Check) bool {
+	if len(d.CheckIds) > 0 && slices.Contains(d.CheckIds, chk.Id) {
+		return true
+	}
+	if d.Host != "" && chk.Meta != nil && chk.Meta[DowntimeHostMetaKey] == d.Host {
+		return true
+	}
+	if len(d.Meta) > 0 {
+		for k, v := range d.Meta {
+			if cv, ok := chk.Meta[k]; !ok || !reflect.DeepEqual(cv, v) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// IsActive returns true if t falls within the Downtime.
+func (d *Downtime) IsActive(t time.Time) bool {
+	if !d.Start.IsZero() && t.Before(d.Start) {
+		return false
+	}
+	if !d.End.IsZero() && !t.Before(d.End) {
+		return false
+	}
+	if d.Recurrence == nil {
+		// a one-off downtime needs both ends
+		return !d.Start.IsZero() && !d.End.IsZero()
+	}
+
+	// active if the recurrence occurred within the last Duration
+	occurrence := d.Recurrence.Next(t.Add(-d.Duration))
+	return !occurrence.IsZero() && !occurrence.After(t)
+}
+
+// DowntimeProvider provides the scheduled downtime for Checks, typically from
+// a database.
+type DowntimeProvider interface {
+	// ActiveDowntime returns the Downtime that applies to chk at time t, or
+	// nil if chk is not in downtime.
+	ActiveDowntime(chk *Check, t time.Time) (*Downtime, error)
+}
+
+// Downtimes is a static, in-memory DowntimeProvider.
+type Downtimes []*Downtime
+
+func (d Downtimes) ActiveDowntime(chk *Check, t time.Time) (*Downtime, error) {
+	for _, downtime := range d {
+		if downtime.Matches(chk) && downtime.IsActive(t) {
+			return downtime, nil
+		}
+	}
+	return nil, nil
+}
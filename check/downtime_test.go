@@ -0,0 +1,96 @@
+package check
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDowntime_Matches(t *testing.T) {
+	chk := New("check1", WithMeta(map[string]any{"host": "router1", "pool": "residential"}))
+
+	tests := []struct {
+		name     string
+		downtime Downtime
+		want     bool
+	}{
+		{name: "empty", downtime: Downtime{}, want: false},
+		{name: "check id", downtime: Downtime{CheckIds: []string{"check0", "check1"}}, want: true},
+		{name: "other check id", downtime: Downtime{CheckIds: []string{"check2"}}, want: false},
+		{name: "host", downtime: Downtime{Host: "router1"}, want: true},
+		{name: "other host", downtime: Downtime{Host: "router2"}, want: false},
+		{name: "meta", downtime: Downtime{Meta: map[string]any{"pool": "residential"}}, want: true},
+		{name: "partial meta", downtime: Downtime{Meta: map[string]any{"pool": "residential", "site": "a"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.downtime.Matches(chk); got != tt.want {
+			t.Errorf("%s: Matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDowntime_IsActive(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	oneOff := Downtime{
+		Start: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC),
+	}
+	recurring, err := NewRecurringDowntime("sunday-maint", "0 2 * * SUN", chicago, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("NewRecurringDowntime(): unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		downtime *Downtime
+		t        time.Time
+		want     bool
+	}{
+		{name: "before one-off", downtime: &oneOff, t: time.Date(2024, 3, 1, 9, 59, 59, 0, time.UTC), want: false},
+		{name: "start of one-off", downtime: &oneOff, t: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), want: true},
+		{name: "end of one-off", downtime: &oneOff, t: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), want: false},
+		{name: "start of recurring", downtime: recurring, t: time.Date(2024, 3, 3, 2, 0, 0, 0, chicago), want: true},
+		{name: "during recurring", downtime: recurring, t: time.Date(2024, 3, 3, 3, 59, 0, 0, chicago), want: true},
+		{name: "end of recurring", downtime: recurring, t: time.Date(2024, 3, 3, 4, 0, 0, 0, chicago), want: false},
+		{name: "wrong day for recurring", downtime: recurring, t: time.Date(2024, 3, 4, 3, 0, 0, 0, chicago), want: false},
+		{name: "recurring in utc", downtime: recurring, t: time.Date(2024, 3, 3, 9, 0, 0, 0, time.UTC), want: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.downtime.IsActive(tt.t); got != tt.want {
+			t.Errorf("%s: IsActive() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheck_ExecuteDoesNotOpenIncidentsInDowntime(t *testing.T) {
+	downtime := &Downtime{
+		CheckIds: []string{"check1"},
+		Start:    time.Now().Add(-time.Minute),
+		End:      time.Now().Add(time.Hour),
+	}
+	cmd := &stateCommand{state: StateCrit}
+	c := New("check1", WithCommand(cmd), WithDowntimeProvider(Downtimes{downtime}))
+
+	_ = c.Execute()
+	if !c.InDowntime || !c.LastResult.InDowntime {
+		t.Error("Execute(): expected check and result to be in downtime")
+	}
+	if c.Incident != nil {
+		t.Errorf("Execute(): unexpected incident %v in downtime", c.Incident)
+	}
+
+	// downtime ended early, still CRIT
+	downtime.End = time.Now()
+	_ = c.Execute()
+	if c.InDowntime {
+		t.Error("Execute(): expected check to be out of downtime")
+	}
+	if c.Incident == nil || c.Incident.ToState != StateCrit {
+		t.Errorf("Execute(): expected CRIT incident once out of downtime, got %v", c.Incident)
+	}
+}
@@ -0,0 +1,368 @@
+package check
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MarshalText encodes the state as its String() value.
+func (s ResultState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a state encoded by MarshalText.
+func (s *ResultState) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "OK", "WARN", "CRIT", "UNKNOWN":
+		*s = NewResultStateFromString(string(text))
+		return nil
+	}
+	return fmt.Errorf("invalid result state %q", text)
+}
+
+// MarshalText encodes the state type as its String() value.
+func (t StateType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a state type encoded by MarshalText.
+func (t *StateType) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "HARD":
+		*t = StateTypeHard
+	case "SOFT":
+		*t = StateTypeSoft
+	default:
+		return fmt.Errorf("invalid state type %q", text)
+	}
+	return nil
+}
+
+// MarshalText encodes the incident type as its String() value.
+func (t IncidentType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes an incident type encoded by MarshalText.
+func (t *IncidentType) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "STATE":
+		*t = IncidentTypeState
+	case "FLAPPING":
+		*t = IncidentTypeFlapping
+	default:
+		return fmt.Errorf("invalid incident type %q", text)
+	}
+	return nil
+}
+
+// MarshalText encodes the event type as its String() value.
+func (t IncidentEventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes an event type encoded by MarshalText.
+func (t *IncidentEventType) UnmarshalText(text []byte) error {
+	for _, et := range []IncidentEventType{
+		IncidentOpened, IncidentEscalated, IncidentAcknowledged, IncidentUnacknowledged, IncidentResolved, IncidentNoted,
+	} {
+		if string(text) == et.String() {
+			*t = et
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid incident event type %q", text)
+}
+
+func (t ResultMetricType) String() string {
+	switch t {
+	case ResultMetricCounter:
+		return "COUNTER"
+	case ResultMetricGauge:
+		return "GAUGE"
+	default:
+		return ""
+	}
+}
+
+// MarshalText encodes the metric type as its String() value.
+func (t ResultMetricType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes a metric type encoded by MarshalText.
+func (t *ResultMetricType) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "COUNTER":
+		*t = ResultMetricCounter
+	case "GAUGE":
+		*t = ResultMetricGauge
+	case "":
+		*t = 0
+	default:
+		return fmt.Errorf("invalid metric type %q", text)
+	}
+	return nil
+}
+
+func (k ResultMetricKind) String() string {
+	switch k {
+	case ResultMetricKindInt64:
+		return "INT64"
+	case ResultMetricKindUint64:
+		return "UINT64"
+	case ResultMetricKindFloat64:
+		return "FLOAT64"
+	default:
+		return ""
+	}
+}
+
+// MarshalText encodes the metric kind as its String() value.
+func (k ResultMetricKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a metric kind encoded by MarshalText.
+func (k *ResultMetricKind) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "INT64":
+		*k = ResultMetricKindInt64
+	case "UINT64":
+		*k = ResultMetricKindUint64
+	case "FLOAT64":
+		*k = ResultMetricKindFloat64
+	case "":
+		*k = ResultMetricKindNone
+	default:
+		return fmt.Errorf("invalid metric kind %q", text)
+	}
+	return nil
+}
+
+// metricAlias has the fields but not the methods of ResultMetric, so
+// encoding/json decodes it field by field.
+type metricAlias ResultMetric
+
+// UnmarshalJSON decodes the metric and parses its Value, as ParseMetric does.
+// Metrics encoded without a kind have it set from their Value.
+func (m *ResultMetric) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*metricAlias)(m)); err != nil {
+		return err
+	}
+	return m.parse()
+}
+
+// resultAlias and incidentAlias have the fields but not the methods of Result
+// and Incident, so gob encodes them field by field.
+type resultAlias Result
+type incidentAlias Incident
+
+// MarshalBinary encodes the Result in a compact binary form using
+// encoding/gob.
+func (r *Result) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode((*resultAlias)(r)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a Result encoded by MarshalBinary.
+func (r *Result) UnmarshalBinary(data []byte) error {
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode((*resultAlias)(r)); err != nil {
+		return err
+	}
+	// gob only decodes the metrics' exported fields
+	for i := range r.Metrics {
+		if err := r.Metrics[i].parse(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalBinary encodes the Incident in a compact binary form using
+// encoding/gob.
+func (i *Incident) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode((*incidentAlias)(i)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an Incident encoded by MarshalBinary.
+func (i *Incident) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode((*incidentAlias)(i))
+}
+
+// checkAlias has the fields but not the methods of Check, so encoding/json
+// encodes it field by field.
+type checkAlias Check
+
+// checkJSON replaces the Check's interface fields with their registered
+// type name and config.
+type checkJSON struct {
+	*checkAlias
+	Schedule json.RawMessage   `json:"schedule,omitempty"`
+	Command  json.RawMessage   `json:"command,omitempty"`
+	Handlers []json.RawMessage `json:"handlers,omitempty"`
+}
+
+// MarshalJSON encodes the Check including its Schedule, Command and Handlers,
+// which must have been registered with RegisterSchedule, RegisterCommand and
+// RegisterHandler.  DependencyResolver and DowntimeProvider are not encoded.
+func (c *Check) MarshalJSON() ([]byte, error) {
+	j := checkJSON{checkAlias: (*checkAlias)(c)}
+
+	var err error
+	if c.Schedule != nil {
+		if j.Schedule, err = MarshalSchedule(c.Schedule); err != nil {
+			return nil, err
+		}
+	}
+	if c.Command != nil {
+		if j.Command, err = MarshalCommand(c.Command); err != nil {
+			return nil, err
+		}
+	}
+	for _, h := range c.Handlers {
+		data, err := MarshalHandler(h)
+		if err != nil {
+			return nil, err
+		}
+		j.Handlers = append(j.Handlers, data)
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a Check encoded by MarshalJSON, constructing its
+// Schedule, Command and Handlers by their registered type names.
+func (c *Check) UnmarshalJSON(data []byte) error {
+	j := checkJSON{checkAlias: (*checkAlias)(c)}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var err error
+	c.Schedule, c.Command, c.Handlers = nil, nil, nil
+	if len(j.Schedule) > 0 {
+		if c.Schedule, err = UnmarshalSchedule(j.Schedule); err != nil {
+			return err
+		}
+	}
+	if len(j.Command) > 0 {
+		if c.Command, err = UnmarshalCommand(j.Command); err != nil {
+			return err
+		}
+	}
+	for _, data := range j.Handlers {
+		h, err := UnmarshalHandler(data)
+		if err != nil {
+			return err
+		}
+		c.Handlers = append(c.Handlers, h)
+	}
+
+	return nil
+}
+
+// cronScheduleJSON is the encoded form of a CronSchedule, which is parsed
+// again from Expression when decoded.
+type cronScheduleJSON struct {
+	Expression string    `json:"expression"`
+	Location   string    `json:"location,omitempty"`
+	Since      time.Time `json:"since"`
+}
+
+func (s *CronSchedule) MarshalJSON() ([]byte, error) {
+	j := cronScheduleJSON{Expression: s.Expression, Since: s.Since}
+	if s.Location != nil {
+		j.Location = s.Location.String()
+	}
+	return json.Marshal(j)
+}
+
+func (s *CronSchedule) UnmarshalJSON(data []byte) error {
+	var j cronScheduleJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	var loc *time.Location
+	if j.Location != "" {
+		var err error
+		if loc, err = time.LoadLocation(j.Location); err != nil {
+			return fmt.Errorf("invalid cron time zone %q: %w", j.Location, err)
+		}
+	}
+	parsed, err := NewCronSchedule(j.Expression, loc)
+	if err != nil {
+		return err
+	}
+	parsed.Since = j.Since
+	*s = *parsed
+	return nil
+}
+
+type spreadScheduleAlias SpreadSchedule
+
+// spreadScheduleJSON encodes the wrapped Schedule by its registered type name.
+type spreadScheduleJSON struct {
+	*spreadScheduleAlias
+	Schedule json.RawMessage `json:"schedule,omitempty"`
+}
+
+func (s *SpreadSchedule) MarshalJSON() ([]byte, error) {
+	j := spreadScheduleJSON{spreadScheduleAlias: (*spreadScheduleAlias)(s)}
+	if s.Schedule != nil {
+		var err error
+		if j.Schedule, err = MarshalSchedule(s.Schedule); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(j)
+}
+
+func (s *SpreadSchedule) UnmarshalJSON(data []byte) (err error) {
+	j := spreadScheduleJSON{spreadScheduleAlias: (*spreadScheduleAlias)(s)}
+	if err = json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	s.Schedule, err = UnmarshalSchedule(j.Schedule)
+	return err
+}
+
+type backoffScheduleAlias BackoffSchedule
+
+// backoffScheduleJSON encodes the wrapped Schedule by its registered type
+// name.
+type backoffScheduleJSON struct {
+	*backoffScheduleAlias
+	Schedule json.RawMessage `json:"schedule,omitempty"`
+}
+
+func (s *BackoffSchedule) MarshalJSON() ([]byte, error) {
+	j := backoffScheduleJSON{backoffScheduleAlias: (*backoffScheduleAlias)(s)}
+	if s.Schedule != nil {
+		var err error
+		if j.Schedule, err = MarshalSchedule(s.Schedule); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(j)
+}
+
+func (s *BackoffSchedule) UnmarshalJSON(data []byte) (err error) {
+	j := backoffScheduleJSON{backoffScheduleAlias: (*backoffScheduleAlias)(s)}
+	if err = json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	s.Schedule, err = UnmarshalSchedule(j.Schedule)
+	return err
+}
@@ -0,0 +1,194 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type encodedCommand struct {
+	Addr  string
+	Count int
+}
+
+func (c *encodedCommand) Run(*Check) (*Result, error) {
+	return NewResult(StateOk, "", nil), nil
+}
+
+type encodedHandler struct {
+	Prefix string
+}
+
+func (h *encodedHandler) Mutate(*Check, *Result, *Incident) {}
+
+func (h *encodedHandler) Process(*Check, *Result, *Incident) error {
+	return nil
+}
+
+func init() {
+	RegisterCommand("test", func() Command { return &encodedCommand{} })
+	RegisterHandler("test", func() Handler { return &encodedHandler{} })
+}
+
+func TestResult_JSONRoundTrip(t *testing.T) {
+	result := NewResult(StateWarn, "HIGH_RTT", []ResultMetric{
+		NewGaugeMetric("avg_rtt", 12.5, ResultMetricUnitMilliseconds).WithTags(map[string]string{"addr": "10.0.0.1"}),
+		NewCounterMetric("octets", 123, ResultMetricUnitOctets),
+	})
+	result.Time = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	result.StateType = StateTypeSoft
+	result.Attempt = 2
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("json.Marshal(): unexpected error: %v", err)
+	}
+
+	var raw map[string]any
+	_ = json.Unmarshal(data, &raw)
+	if raw["state"] != "WARN" || raw["stateType"] != "SOFT" {
+		t.Errorf("json.Marshal(): expected readable state and state type, got %s", data)
+	}
+
+	var decoded Result
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal(): unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*result, decoded) {
+		t.Errorf("json round trip: expected %+v, got %+v", *result, decoded)
+	}
+}
+
+func TestResultAndIncident_BinaryRoundTrip(t *testing.T) {
+	result := NewResult(StateCrit, "DOWN", []ResultMetric{NewGaugeMetric("loss", 100, ResultMetricUnitPercent)})
+	incident := MakeFlappingIncident(nil, result)
+	incident.Acknowledge()
+
+	data, err := result.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Result.MarshalBinary(): unexpected error: %v", err)
+	}
+	var decodedResult Result
+	if err := decodedResult.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Result.UnmarshalBinary(): unexpected error: %v", err)
+	}
+	if !decodedResult.Time.Equal(result.Time) {
+		t.Errorf("binary round trip: expected time %v, got %v", result.Time, decodedResult.Time)
+	}
+	decodedResult.Time = result.Time
+	if !reflect.DeepEqual(*result, decodedResult) {
+		t.Errorf("binary round trip: expected %+v, got %+v", *result, decodedResult)
+	}
+
+	data, err = incident.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Incident.MarshalBinary(): unexpected error: %v", err)
+	}
+	var decodedIncident Incident
+	if err := decodedIncident.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Incident.UnmarshalBinary(): unexpected error: %v", err)
+	}
+	if decodedIncident.Id != incident.Id || decodedIncident.Type != IncidentTypeFlapping ||
+		decodedIncident.ToState != StateCrit || !decodedIncident.IsAcknowledged() {
+		t.Errorf("binary round trip: expected %+v, got %+v", *incident, decodedIncident)
+	}
+}
+
+func TestResultState_UnmarshalTextRejectsUnknownStates(t *testing.T) {
+	var s ResultState
+	if err := s.UnmarshalText([]byte("BROKEN")); err == nil {
+		t.Error("UnmarshalText(): expected error for unknown state")
+	}
+	if err := s.UnmarshalText([]byte("CRIT")); err != nil || s != StateCrit {
+		t.Errorf("UnmarshalText(): expected CRIT, got %v (%v)", s, err)
+	}
+}
+
+func TestCheck_JSONRoundTrip(t *testing.T) {
+	lastCheck := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastResult := NewResult(StateCrit, "DOWN", nil)
+	lastResult.Time = lastCheck
+
+	chk := New("check-1",
+		WithCommand(&encodedCommand{Addr: "10.0.0.1", Count: 5}),
+		WithSchedule(&BackoffSchedule{
+			Schedule: &SpreadSchedule{
+				Schedule: MustNewCronSchedule("*/5 * * * *", time.UTC),
+				Interval: time.Minute,
+				Since:    since,
+			},
+			Factor: 3,
+			States: []ResultState{StateCrit},
+		}),
+		WithHandlers([]Handler{&encodedHandler{Prefix: "poller."}}),
+		WithMeta(map[string]any{"host": "router1"}),
+		WithTimeout(10*time.Second),
+		WithFlapDetection(20, 30),
+	)
+	chk.LastCheck = &lastCheck
+	chk.LastResult = lastResult
+	chk.Incident = MakeIncidentFromResults(nil, lastResult)
+	chk.StateHistory = []ResultState{StateOk, StateCrit}
+
+	data, err := json.Marshal(chk)
+	if err != nil {
+		t.Fatalf("json.Marshal(): unexpected error: %v", err)
+	}
+
+	var decoded Check
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal(): unexpected error: %v", err)
+	}
+
+	if cmd, ok := decoded.Command.(*encodedCommand); !ok || *cmd != (encodedCommand{Addr: "10.0.0.1", Count: 5}) {
+		t.Errorf("json round trip: unexpected command %#v", decoded.Command)
+	}
+	if len(decoded.Handlers) != 1 || decoded.Handlers[0].(*encodedHandler).Prefix != "poller." {
+		t.Errorf("json round trip: unexpected handlers %#v", decoded.Handlers)
+	}
+	backoff, ok := decoded.Schedule.(*BackoffSchedule)
+	if !ok || backoff.Factor != 3 || !reflect.DeepEqual(backoff.States, []ResultState{StateCrit}) {
+		t.Fatalf("json round trip: unexpected schedule %#v", decoded.Schedule)
+	}
+	spread, ok := backoff.Schedule.(*SpreadSchedule)
+	if !ok || spread.Interval != time.Minute || !spread.Since.Equal(since) {
+		t.Fatalf("json round trip: unexpected wrapped schedule %#v", backoff.Schedule)
+	}
+	cron, ok := spread.Schedule.(*CronSchedule)
+	if !ok || cron.Expression != "*/5 * * * *" || cron.Location != time.UTC {
+		t.Fatalf("json round trip: unexpected cron schedule %#v", spread.Schedule)
+	}
+	if want := time.Date(2024, 1, 2, 3, 5, 0, 0, time.UTC); !cron.Next(lastCheck).Equal(want) {
+		t.Errorf("json round trip: expected decoded cron to be due at %v, got %v", want, cron.Next(lastCheck))
+	}
+
+	if decoded.Id != "check-1" || decoded.Timeout != 10*time.Second || decoded.Meta["host"] != "router1" ||
+		!reflect.DeepEqual(decoded.FlapDetection, chk.FlapDetection) ||
+		!reflect.DeepEqual(decoded.StateHistory, chk.StateHistory) ||
+		!decoded.LastCheck.Equal(lastCheck) {
+		t.Errorf("json round trip: unexpected check %+v", decoded)
+	}
+	if decoded.LastResult == nil || decoded.LastResult.Id != lastResult.Id || decoded.LastResult.State != StateCrit {
+		t.Errorf("json round trip: unexpected last result %+v", decoded.LastResult)
+	}
+	if decoded.Incident == nil || decoded.Incident.Id != chk.Incident.Id {
+		t.Errorf("json round trip: unexpected incident %+v", decoded.Incident)
+	}
+}
+
+func TestCheck_MarshalJSONFailsForUnregisteredCommand(t *testing.T) {
+	chk := New("check-1", WithCommand(&testCommand{}))
+
+	if _, err := json.Marshal(chk); !errors.Is(err, ErrUnregisteredType) {
+		t.Errorf("json.Marshal(): expected ErrUnregisteredType, got %v", err)
+	}
+}
+
+func TestUnmarshalCommandFailsForUnregisteredType(t *testing.T) {
+	if _, err := UnmarshalCommand([]byte(`{"type":"nope"}`)); !errors.Is(err, ErrUnregisteredType) {
+		t.Errorf("UnmarshalCommand(): expected ErrUnregisteredType, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package check
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Dialer dials network connections, such as a *net.Dialer.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Resolver answers DNS queries, such as a *net.Resolver.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// Environment is the set of dependencies Commands and Handlers use to reach
+// the outside world.  A server.Server passes its Environment to every Check
+// it executes, so a whole fleet of Checks can be switched to fakes for tests
+// or to tuned clients in production without touching each Command.
+//
+// Any dependency left unset falls back to the standard library (or the
+// package's default implementation).  Dependencies specific to a Command or
+// Handler package, such as an SNMP getter or pinger, are provided with
+// WithDependency, typically through a helper in that package (ex.
+// snmp.WithGetter).
+type Environment struct {
+	// Clock is used in place of time.Now().
+	Clock Clock
+
+	// HTTPClient is used by Commands making HTTP requests.
+	HTTPClient *http.Client
+
+	// NewResolver creates the Resolver that queries the DNS server at address
+	// (host:port) for Commands configured to query it.  When it is nil,
+	// Commands query the server with a *net.Resolver dialing through Dialer.
+	NewResolver func(address string) Resolver
+
+	// Dialer dials the connections Commands and Handlers make.
+	Dialer Dialer
+
+	dependencies map[reflect.Type]any
+}
+
+// EnvOption configures an Environment.
+type EnvOption func(*Environment)
+
+// NewEnvironment creates an Environment with the provided EnvOptions.
+func NewEnvironment(options ...EnvOption) *Environment {
+	env := &Environment{}
+
+	for _, option := range options {
+		option(env)
+	}
+
+	return env
+}
+
+func WithClock(clock Clock) EnvOption {
+	return func(e *Environment) {
+		e.Clock = clock
+	}
+}
+
+func WithHTTPClient(client *http.Client) EnvOption {
+	return func(e *Environment) {
+		e.HTTPClient = client
+	}
+}
+
+func WithResolver(newResolver func(address string) Resolver) EnvOption {
+	return func(e *Environment) {
+		e.NewResolver = newResolver
+	}
+}
+
+func WithDialer(dialer Dialer) EnvOption {
+	return func(e *Environment) {
+		e.Dialer = dialer
+	}
+}
+
+// WithDependency provides v as the Environment's dependency of type T, which
+// is usually an interface type (ex. WithDependency[snmp.Getter](getter)).
+func WithDependency[T any](v T) EnvOption {
+	return func(e *Environment) {
+		if e.dependencies == nil {
+			e.dependencies = make(map[reflect.Type]any)
+		}
+		e.dependencies[reflect.TypeOf((*T)(nil)).Elem()] = v
+	}
+}
+
+// Dependency returns env's dependency of type T, if it was provided with
+// WithDependency.
+func Dependency[T any](env *Environment) (T, bool) {
+	var v T
+	if env == nil {
+		return v, false
+	}
+	v, ok := env.dependencies[reflect.TypeOf((*T)(nil)).Elem()].(T)
+	return v, ok
+}
+
+// Now returns the Environment's Clock's time, or time.Now() if it has no
+// Clock.
+func (e *Environment) Now() time.Time {
+	if e == nil || e.Clock == nil {
+		return time.Now()
+	}
+	return e.Clock.Now()
+}
+
+// DialContext dials address with the Environment's Dialer, or with a
+// net.Dialer if it has none.  A non-zero timeout limits how long the dial
+// may take.
+func (e *Environment) DialContext(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	if e == nil || e.Dialer == nil {
+		d := net.Dialer{Timeout: timeout}
+		return d.DialContext(ctx, network, address)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return e.Dialer.DialContext(ctx, network, address)
+}
+
+// defaultEnvironment is the Environment of Checks that were not given one.
+var defaultEnvironment = &Environment{}
+
+// WithEnvironment sets the Environment the Check's Command and Handlers run
+// in.  See Check.Environment.
+func WithEnvironment(env *Environment) Option {
+	return func(c *Check) {
+		c.env = env
+	}
+}
+
+// SetEnvironment sets the Environment the Check's Command and Handlers run
+// in.  It should not be called while the Check is executing.
+func (c *Check) SetEnvironment(env *Environment) {
+	c.env = env
+}
+
+// HasEnvironment returns true if the Check was given an Environment.
+func (c *Check) HasEnvironment() bool {
+	return c.env != nil
+}
+
+// Environment returns the Environment the Check's Command and Handlers should
+// get their dependencies from.  It is never nil; a Check that was not given
+// one has an empty Environment.
+func (c *Check) Environment() *Environment {
+	if c.env != nil {
+		return c.env
+	}
+	return defaultEnvironment
+}
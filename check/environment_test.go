@@ -0,0 +1,100 @@
+package check
+
+import (
+	"testing"
+	"time"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+type namer interface {
+	Name() string
+}
+
+type staticNamer string
+
+func (n staticNamer) Name() string {
+	return string(n)
+}
+
+func TestEnvironment_Dependency(t *testing.T) {
+	env := NewEnvironment(WithDependency[namer](staticNamer("foo")))
+
+	if n, ok := Dependency[namer](env); !ok || n.Name() != "foo" {
+		t.Errorf("Dependency(): expected provided namer, got %v, %v", n, ok)
+	}
+	if _, ok := Dependency[Clock](env); ok {
+		t.Error("Dependency(): expected no Clock to be provided")
+	}
+	if _, ok := Dependency[namer](nil); ok {
+		t.Error("Dependency(): expected nil Environment to provide nothing")
+	}
+}
+
+func TestCheck_ExecuteUsesEnvironmentClock(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	c := New("check-1", WithCommand(&stateCommand{state: StateOk}), WithEnvironment(NewEnvironment(WithClock(fixedClock(now)))))
+
+	_ = c.Execute()
+
+	if c.LastCheck == nil || !c.LastCheck.Equal(now) {
+		t.Errorf("Execute(): expected LastCheck %s, got %v", now, c.LastCheck)
+	}
+}
+
+func TestCheck_ExecuteTimesResultsAndIncidentsWithEnvironmentClock(t *testing.T) {
+	opened := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	cmd := &stateCommand{state: StateCrit}
+	env := NewEnvironment(WithClock(fixedClock(opened)))
+	c := New("check-1", WithCommand(cmd), WithEnvironment(env))
+
+	_ = c.Execute()
+	if !c.LastResult.Time.Equal(opened) {
+		t.Errorf("Execute(): expected result time %s, got %s", opened, c.LastResult.Time)
+	}
+	if c.Incident == nil || !c.Incident.Time.Equal(opened) {
+		t.Fatalf("Execute(): expected incident opened at %s, got %v", opened, c.Incident)
+	}
+	c.Incident.AcknowledgeBy("alice", "", WithAckExpiry(opened.Add(time.Hour)))
+
+	resolved := opened.Add(2 * time.Hour)
+	env.Clock = fixedClock(resolved)
+	if !c.Incident.IsAcknowledgedAt(opened) || c.Incident.IsAcknowledgedAt(resolved) {
+		t.Error("IsAcknowledgedAt(): expected the acknowledgement to expire an hour after it opened")
+	}
+	cmd.state = StateOk
+	_ = c.Execute()
+	if c.Incident.Resolved == nil || !c.Incident.Resolved.Equal(resolved) {
+		t.Errorf("Execute(): expected incident resolved at %s, got %v", resolved, c.Incident.Resolved)
+	}
+}
+
+func TestCheck_IsDueUsesEnvironmentClock(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	env := NewEnvironment(WithClock(fixedClock(now)))
+	c := New("check-1", WithPeriodicSchedule(60), WithEnvironment(env))
+
+	if got := c.DueAt(); !got.Equal(now) {
+		t.Errorf("DueAt(): expected a check never run to be due at %s, got %s", now, got)
+	}
+	lastCheck := now.Add(-30 * time.Second)
+	c.LastCheck = &lastCheck
+	if c.IsDue() {
+		t.Error("IsDue(): expected check not to be due for another 30 seconds")
+	}
+	env.Clock = fixedClock(now.Add(30 * time.Second))
+	if !c.IsDue() {
+		t.Error("IsDue(): expected check to be due")
+	}
+}
+
+func TestCheck_EnvironmentIsNeverNil(t *testing.T) {
+	c := &Check{}
+	if c.HasEnvironment() || c.Environment() == nil {
+		t.Error("Environment(): expected an empty Environment for a Check without one")
+	}
+}
@@ -0,0 +1,83 @@
+package check
+
+// DefaultFlapWindowSize is the number of recent states considered for flap
+// detection when FlapDetection.WindowSize is not set.
+const DefaultFlapWindowSize = 21
+
+// FlapDetection configures how a Check detects that its state is flapping.
+// Each time the Check executes, its state is appended to a sliding window of
+// recent states and the percentage of state changes within the window is
+// computed.  Recent changes are weighted more heavily than older ones.  The
+// Check starts flapping once the percentage reaches HighThreshold and stops
+// once it drops below LowThreshold.
+type FlapDetection struct {
+	// LowThreshold is the percent state change below which a flapping Check
+	// stops flapping.
+	LowThreshold float64 `json:"lowThreshold"`
+
+	// HighThreshold is the percent state change at or above which a Check
+	// starts flapping.
+	HighThreshold float64 `json:"highThreshold"`
+
+	// WindowSize is the number of recent states considered.
+	WindowSize int `json:"windowSize,omitempty"`
+}
+
+// PercentStateChange computes the weighted percent state change of states
+// (oldest first) over the window.  The weights increase linearly from 0.8 for
+// the oldest transition in the window to 1.2 for the newest.  While fewer
+// states than the window size have been seen, the missing ones count as
+// unchanged.
+func (f FlapDetection) PercentStateChange(states []ResultState) float64 {
+	n := f.windowSize()
+	if len(states) > n {
+		states = states[len(states)-n:]
+	}
+	offset := n - len(states)
+
+	var changes float64
+	for i := 1; i < len(states); i++ {
+		if states[i] == states[i-1] {
+			continue
+		}
+		weight := 1.0
+		if n > 2 {
+			weight = 0.8 + 0.4*float64(offset+i-1)/float64(n-2)
+		}
+		changes += weight
+	}
+
+	return changes / float64(n-1) * 100
+}
+
+func (f FlapDetection) windowSize() int {
+	if f.WindowSize < 2 {
+		return DefaultFlapWindowSize
+	}
+	return f.WindowSize
+}
+
+// detectFlapping records result's state in the Check's StateHistory and updates IsFlapping and PercentStateChange.
+// It returns true if the Check just started flapping.
+func (c *Check) detectFlapping(result *Result) bool {
+	if c.FlapDetection == nil || result.UnreachableVia != "" {
+		return false
+	}
+
+	c.StateHistory = append(c.StateHistory, result.State)
+	if n := c.FlapDetection.windowSize(); len(c.StateHistory) > n {
+		c.StateHistory = c.StateHistory[len(c.StateHistory)-n:]
+	}
+	c.PercentStateChange = c.FlapDetection.PercentStateChange(c.StateHistory)
+
+	wasFlapping := c.IsFlapping
+	if wasFlapping && c.PercentStateChange < c.FlapDetection.LowThreshold {
+		c.IsFlapping = false
+		c.Debugf("stopped flapping (%.2f%% state change)", c.PercentStateChange)
+	} else if !wasFlapping && c.PercentStateChange >= c.FlapDetection.HighThreshold {
+		c.IsFlapping = true
+		c.Debugf("started flapping (%.2f%% state change)", c.PercentStateChange)
+	}
+
+	return !wasFlapping && c.IsFlapping
+}
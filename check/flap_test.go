@@ -0,0 +1,74 @@
+package check
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFlapDetection_PercentStateChange(t *testing.T) {
+	f := FlapDetection{WindowSize: 3}
+	tests := []struct {
+		states []ResultState
+		want   float64
+	}{
+		{states: nil, want: 0},
+		{states: []ResultState{StateCrit}, want: 0},
+		{states: []ResultState{StateOk, StateOk, StateOk}, want: 0},
+		{states: []ResultState{StateOk, StateCrit, StateOk}, want: 100},
+		{states: []ResultState{StateOk, StateCrit, StateCrit}, want: 40},       // oldest transition weighs 0.8
+		{states: []ResultState{StateOk, StateOk, StateCrit}, want: 60},         // newest transition weighs 1.2
+		{states: []ResultState{StateOk, StateWarn, StateCrit}, want: 100},      // non-OK to non-OK is a change too
+		{states: []ResultState{StateOk, StateCrit}, want: 60},                  // missing history counts as unchanged
+		{states: []ResultState{StateCrit, StateOk, StateOk, StateOk}, want: 0}, // only the window counts
+	}
+
+	for _, tt := range tests {
+		got := f.PercentStateChange(tt.states)
+		if math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("PercentStateChange(%v) = %v, want %v", tt.states, got, tt.want)
+		}
+	}
+}
+
+func TestCheck_ExecuteOpensAndResolvesFlappingIncident(t *testing.T) {
+	cmd := &stateCommand{}
+	c := New("flappy", WithCommand(cmd), WithFlapDetection(20, 50))
+	c.FlapDetection.WindowSize = 5
+
+	// OK, CRIT, OK, CRIT... starts flapping on the third execution
+	for i, state := range []ResultState{StateOk, StateCrit, StateOk} {
+		cmd.state = state
+		_ = c.Execute()
+		if i < 2 && c.IsFlapping {
+			t.Fatalf("Execute(): flapping too early on execution %d", i+1)
+		}
+	}
+	if !c.IsFlapping {
+		t.Fatalf("Execute(): expected check to be flapping, percent state change %v", c.PercentStateChange)
+	}
+	if c.Incident == nil || c.Incident.Type != IncidentTypeFlapping || c.Incident.IsResolved() {
+		t.Fatalf("Execute(): expected open flapping incident, got %v", c.Incident)
+	}
+	flappingIncident := c.Incident
+
+	// state changes while flapping do not open new incidents
+	cmd.state = StateCrit
+	_ = c.Execute()
+	if c.Incident != flappingIncident || c.Incident.IsResolved() {
+		t.Fatalf("Execute(): expected flapping incident to remain open, got %v", c.Incident)
+	}
+
+	// settle on CRIT until flapping stops, which resolves the flapping incident and opens a state incident
+	for i := 0; i < 5 && c.IsFlapping; i++ {
+		_ = c.Execute()
+	}
+	if c.IsFlapping {
+		t.Fatalf("Execute(): expected check to stop flapping, percent state change %v", c.PercentStateChange)
+	}
+	if !flappingIncident.IsResolved() {
+		t.Error("Execute(): expected flapping incident to be resolved")
+	}
+	if c.Incident == nil || c.Incident.Type != IncidentTypeState || c.Incident.ToState != StateCrit {
+		t.Errorf("Execute(): expected CRIT state incident, got %v", c.Incident)
+	}
+}
@@ -0,0 +1,149 @@
+package derived
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-multierror"
+	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
+	"log/slog"
+	"sync"
+)
+
+func init() {
+	check.RegisterHandler("derived", func() check.Handler { return &Handler{} })
+}
+
+// Metric defines a ResultMetric computed from an expression over a Result's
+// other metrics (see Compile).
+type Metric struct {
+	// Label is the derived metric's label.
+	Label string
+
+	// Expression computes the metric's value.
+	Expression string
+
+	// Type is the derived metric's type (default gauge).
+	Type check.ResultMetricType
+
+	// Unit and Tags are set on the derived metric.
+	Unit check.ResultMetricUnit
+	Tags map[string]string
+
+	// Threshold, when set, is evaluated against the derived value.  If it
+	// produces a worse state than the Result's, the Result takes its state
+	// and reason code.
+	Threshold *threshold.Threshold
+}
+
+// NewMetric creates a gauge Metric.
+func NewMetric(label, expression string, unit check.ResultMetricUnit) Metric {
+	return Metric{
+		Label:      label,
+		Expression: expression,
+		Type:       check.ResultMetricGauge,
+		Unit:       unit,
+	}
+}
+
+// Handler appends metrics derived from a Result's metrics (ex. bits per second
+// from octet counters, or percent used from used and total) to the Result.
+//
+// Metrics are computed in order, so a Metric may refer to the ones before
+// it.  A Metric whose expression fails to evaluate, such as when a metric it
+// refers to is missing, is left out of the Result.
+//
+// Derived metrics are computed before the Check determines the Result's state
+// type and Incident, so that thresholds on them are treated the same as the
+// Command's own.
+//
+// The Metrics' expressions are compiled once, by NewHandler or when the
+// Handler is decoded from JSON (or else when it first mutates a Result), and
+// must not be changed afterwards.
+type Handler struct {
+	Metrics []Metric
+
+	compileOnce sync.Once
+	// exprs holds each of Metrics' compiled Expression, or nil if it failed to compile
+	exprs []*Expression
+}
+
+// NewHandler creates a Handler deriving metrics.  It returns an error if any
+// of the metrics' expressions fail to compile.
+func NewHandler(metrics ...Metric) (*Handler, error) {
+	h := &Handler{Metrics: metrics}
+	if err := h.compile(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Handler) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Metrics []Metric
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*h = Handler{Metrics: v.Metrics}
+	return h.compile()
+}
+
+// compile compiles the Metrics' expressions the first time it is called,
+// returning the errors of those that failed.
+func (h *Handler) compile() error {
+	var err error
+	h.compileOnce.Do(func() {
+		h.exprs = make([]*Expression, len(h.Metrics))
+		for i, m := range h.Metrics {
+			expr, errC := Compile(m.Expression)
+			if errC != nil {
+				err = multierror.Append(err, fmt.Errorf("derived metric %s: %w", m.Label, errC))
+				continue
+			}
+			h.exprs[i] = expr
+		}
+	})
+	return err
+}
+
+func (h *Handler) MutateResult(chk *check.Check, result *check.Result) {
+	// only a Handler that was not created by NewHandler or decoded can fail here, and only the first time
+	if err := h.compile(); err != nil {
+		chk.Logger().Error("derived metrics failed to compile", slog.Any("error", err))
+	}
+
+	for i, m := range h.Metrics {
+		expr := h.exprs[i]
+		if expr == nil {
+			continue
+		}
+		value, err := expr.Eval(chk, result)
+		if err != nil {
+			chk.Debugf("derived metric %s not computed: %v", m.Label, err)
+			continue
+		}
+
+		metricType := m.Type
+		if metricType == 0 {
+			metricType = check.ResultMetricGauge
+		}
+		metric := check.NewGaugeMetric(m.Label, value, m.Unit)
+		metric.Type = metricType
+		metric.Tags = m.Tags
+		result.Metrics = append(result.Metrics, metric)
+
+		if s, r := threshold.Evaluate(m.Threshold, value, "THRESHOLD_EXCEEDED"); s != check.StateOk && s.Overrides(result.State) {
+			chk.Debugf("derived metric %s state (%s, %s) overrides result state (%s, %s)", m.Label, s, r, result.State, result.ReasonCode)
+			result.State, result.ReasonCode = s, r
+		}
+	}
+}
+
+func (h *Handler) Mutate(*check.Check, *check.Result, *check.Incident) {
+	return
+}
+
+func (h *Handler) Process(*check.Check, *check.Result, *check.Incident) error {
+	return nil
+}
@@ -0,0 +1,203 @@
+package derived
+
+import (
+	"errors"
+	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/check/threshold"
+	"testing"
+	"time"
+)
+
+func TestExpressionEval(t *testing.T) {
+	lastTime := time.Unix(1000, 0)
+	chk := &check.Check{
+		LastResult: &check.Result{
+			Time: lastTime,
+			Metrics: []check.ResultMetric{
+				check.NewCounterMetric("ifHCInOctets", 1000, check.ResultMetricUnitOctets),
+				check.NewCounterMetric("ifHCInOctets", 5000, check.ResultMetricUnitOctets).WithTags(map[string]string{"ifIndex": "3"}),
+			},
+		},
+	}
+	result := &check.Result{
+		Time: lastTime.Add(10 * time.Second),
+		Metrics: []check.ResultMetric{
+			check.NewCounterMetric("ifHCInOctets", 2000, check.ResultMetricUnitOctets),
+			check.NewCounterMetric("ifHCInOctets", 5500, check.ResultMetricUnitOctets).WithTags(map[string]string{"ifIndex": "3"}),
+			check.NewGaugeMetric("used", 30, check.ResultMetricUnitBytes),
+			check.NewGaugeMetric("total", 120, check.ResultMetricUnitBytes),
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-2 * -3 - 1", 5},
+		{"7 % 4", 3},
+		{"1.5e2", 150},
+		{"used / total * 100", 25},
+		{"used + total", 150},
+		{"rate(ifHCInOctets) * 8", 800},
+		{`rate(ifHCInOctets{ifIndex="3"})`, 50},
+		{"delta(ifHCInOctets)", 1000},
+		{"prev(ifHCInOctets)", 1000},
+		{"min(used, total, 50)", 30},
+		{"max(used, total, 50)", 120},
+		{"abs(used - total)", 90},
+		{"used > 20 && total < 200", 1},
+		{"used > 50 || !(total == 120)", 0},
+		{"used >= 30 ? 1 : 2", 1},
+		{"used != 30 ? 1 : used < 30 ? 2 : 3", 3},
+		{"if(total > 0, used / total, 0)", 0.25},
+		{"used > 100 && missing", 0}, // the missing metric is never evaluated
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile(): unexpected error: %v", err)
+			}
+			got, err := expr.Eval(chk, result)
+			if err != nil {
+				t.Fatalf("Eval(): unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(): expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExpressionEvalErrors(t *testing.T) {
+	chk := &check.Check{}
+	result := &check.Result{Metrics: []check.ResultMetric{check.NewGaugeMetric("zero", 0, check.ResultMetricUnitNone)}}
+
+	if _, err := MustCompile("missing * 2").Eval(chk, result); !errors.Is(err, ErrMetricNotFound) {
+		t.Errorf("Eval(): expected ErrMetricNotFound, got %v", err)
+	}
+	if _, err := MustCompile("rate(zero)").Eval(chk, result); !errors.Is(err, ErrMetricNotFound) {
+		t.Errorf("Eval(): expected ErrMetricNotFound without a previous result, got %v", err)
+	}
+	if _, err := MustCompile("1 / zero").Eval(chk, result); err == nil {
+		t.Error("Eval(): expected division by zero error")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"rate(1 + 2)",
+		"unknown(1)",
+		"if(1, 2)",
+		`m{tag=unquoted}`,
+		`"unterminated`,
+		"1 ? 2",
+		"1 # 2",
+	} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Compile(%q): expected error", expr)
+		}
+	}
+}
+
+func TestHandlerAppendsDerivedMetrics(t *testing.T) {
+	h, err := NewHandler(
+		NewMetric("percent_used", "used / total * 100", check.ResultMetricUnitPercent),
+		NewMetric("percent_free", "100 - percent_used", check.ResultMetricUnitPercent),
+		NewMetric("broken", "missing + 1", check.ResultMetricUnitNone),
+	)
+	if err != nil {
+		t.Fatalf("NewHandler(): unexpected error: %v", err)
+	}
+	result := check.NewResult(check.StateOk, "", []check.ResultMetric{
+		check.NewGaugeMetric("used", 30, check.ResultMetricUnitBytes),
+		check.NewGaugeMetric("total", 120, check.ResultMetricUnitBytes),
+	})
+
+	h.MutateResult(&check.Check{}, result)
+
+	if len(result.Metrics) != 4 {
+		t.Fatalf("MutateResult(): expected 2 derived metrics, got %v", result.Metrics[2:])
+	}
+	want := []check.ResultMetric{
+		{Label: "percent_used", Value: "25", Type: check.ResultMetricGauge, Unit: check.ResultMetricUnitPercent},
+		{Label: "percent_free", Value: "75", Type: check.ResultMetricGauge, Unit: check.ResultMetricUnitPercent},
+	}
+	for i, m := range want {
+		if got := result.Metrics[2+i]; got.Label != m.Label || got.Value != m.Value || got.Type != m.Type || got.Unit != m.Unit {
+			t.Errorf("MutateResult(): expected %v, got %v", m, got)
+		}
+	}
+}
+
+func TestHandlerThresholdOpensIncident(t *testing.T) {
+	m := NewMetric("percent_used", "used / total * 100", check.ResultMetricUnitPercent)
+	m.Threshold = threshold.MustNew("80", "90", "DISK_FULL")
+	h, err := NewHandler(m)
+	if err != nil {
+		t.Fatalf("NewHandler(): unexpected error: %v", err)
+	}
+	chk := check.New("check-1",
+		check.WithCommand(&metricsCommand{metrics: []check.ResultMetric{
+			check.NewGaugeMetric("used", 95, check.ResultMetricUnitBytes),
+			check.NewGaugeMetric("total", 100, check.ResultMetricUnitBytes),
+		}}),
+		check.WithHandlers([]check.Handler{h}),
+	)
+
+	_ = chk.Execute()
+
+	if chk.LastResult.State != check.StateCrit || chk.LastResult.ReasonCode != "DISK_FULL" {
+		t.Errorf("Execute(): expected CRIT/DISK_FULL, got %s/%s", chk.LastResult.State, chk.LastResult.ReasonCode)
+	}
+	if chk.Incident == nil || chk.Incident.ToState != check.StateCrit {
+		t.Errorf("Execute(): expected CRIT incident, got %v", chk.Incident)
+	}
+}
+
+func TestHandlerRejectsInvalidExpressions(t *testing.T) {
+	if _, err := NewHandler(NewMetric("percent_used", "used / / total", check.ResultMetricUnitPercent)); err == nil {
+		t.Error("NewHandler(): expected an error for an invalid expression")
+	}
+
+	data := []byte(`{"type":"derived","config":{"Metrics":[{"Label":"percent_used","Expression":"used /"}]}}`)
+	if _, err := check.UnmarshalHandler(data); err == nil {
+		t.Error("UnmarshalHandler(): expected an error for an invalid expression")
+	}
+
+	h, err := NewHandler(NewMetric("percent_used", "used / total * 100", check.ResultMetricUnitPercent))
+	if err != nil {
+		t.Fatalf("NewHandler(): unexpected error: %v", err)
+	}
+	data, err = check.MarshalHandler(h)
+	if err != nil {
+		t.Fatalf("MarshalHandler(): unexpected error: %v", err)
+	}
+	decoded, err := check.UnmarshalHandler(data)
+	if err != nil {
+		t.Fatalf("UnmarshalHandler(): unexpected error: %v", err)
+	}
+	result := check.NewResult(check.StateOk, "", []check.ResultMetric{
+		check.NewGaugeMetric("used", 30, check.ResultMetricUnitBytes),
+		check.NewGaugeMetric("total", 120, check.ResultMetricUnitBytes),
+	})
+	decoded.(*Handler).MutateResult(&check.Check{}, result)
+	if m := result.Metric("percent_used", nil); m == nil || m.Value != "25" {
+		t.Errorf("MutateResult(): expected decoded handler to derive percent_used of 25, got %v", m)
+	}
+}
+
+type metricsCommand struct {
+	metrics []check.ResultMetric
+}
+
+func (c *metricsCommand) Run(*check.Check) (*check.Result, error) {
+	return check.NewResult(check.StateOk, "", c.metrics), nil
+}
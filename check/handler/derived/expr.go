@@ -0,0 +1,621 @@
+package derived
+
+import (
+	"errors"
+	"fmt"
+	"github.com/seankndy/gopoller/check"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrMetricNotFound is returned (wrapped) when an Expression references a
+// metric that the Result (or, for rate(), delta() and prev(), the Check's
+// previous Result) does not have.
+var ErrMetricNotFound = errors.New("metric not found")
+
+// Expression is a compiled metric expression.  See Compile for its syntax.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses an expression over a Result's metrics.  Expressions support:
+//
+//   - numbers, and metric references by label (ex. ifHCInOctets), optionally
+//     selecting by tags (ex. ifHCInOctets{ifIndex="3"})
+//   - arithmetic: + - * / % and parentheses
+//   - comparisons and logic, which produce 1 or 0: < <= > >= == != && || !
+//     (&& and || short-circuit, so "total > 0 && used / total > 0.9" is 0
+//     rather than an error when total is 0; use a conditional such as
+//     "total > 0 ? used / total : 0" to select a value)
+//   - conditionals: cond ? a : b, or if(cond, a, b)
+//   - min(a, b, ...), max(a, b, ...) and abs(x)
+//   - rate(metric), the metric's per-second rate of change since the
+//     Check's previous Result, delta(metric), its change since the previous
+//     Result, and prev(metric), its value in the previous Result
+func Compile(expr string) (*Expression, error) {
+	p := &parser{lexer: lexer{src: expr}}
+	p.next()
+	root, err := p.parseExpr()
+	if err == nil && p.err != nil {
+		err = p.err
+	}
+	if err == nil && p.tok.kind != tokEOF {
+		err = p.errorf("unexpected %q", p.tok.text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	return &Expression{source: expr, root: root}, nil
+}
+
+// MustCompile is like Compile but panics if expr cannot be parsed.
+func MustCompile(expr string) *Expression {
+	e, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Eval evaluates the expression against result, the current Result of chk.
+// The previous values used by rate(), delta() and prev() come from the
+// Check's History when it has one, and otherwise from its LastResult.
+func (e *Expression) Eval(chk *check.Check, result *check.Result) (float64, error) {
+	return e.root.eval(&env{chk: chk, result: result})
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// env is what an Expression is evaluated against.
+type env struct {
+	chk    *check.Check
+	result *check.Result
+}
+
+func (e *env) current(ref *metricRef) (check.ResultMetric, error) {
+	if m := e.result.Metric(ref.label, ref.tags); m != nil {
+		return *m, nil
+	}
+	return check.ResultMetric{}, fmt.Errorf("%w: %s", ErrMetricNotFound, ref)
+}
+
+// previous returns the metric's previous value and how many seconds before
+// the current Result it was collected.
+func (e *env) previous(ref *metricRef) (check.ResultMetric, float64, error) {
+	var m *check.ResultMetric
+	var last *check.Result
+	if e.chk != nil && e.chk.History != nil {
+		for i := e.chk.History.Len() - 1; i >= 0 && m == nil; i-- {
+			last = e.chk.History.At(i)
+			m = last.Metric(ref.label, ref.tags)
+		}
+	} else if e.chk != nil && e.chk.LastResult != nil {
+		last = e.chk.LastResult
+		m = last.Metric(ref.label, ref.tags)
+	}
+	if m == nil {
+		return check.ResultMetric{}, 0, fmt.Errorf("%w: previous %s", ErrMetricNotFound, ref)
+	}
+	return *m, e.result.Time.Sub(last.Time).Seconds(), nil
+}
+
+type node interface {
+	eval(*env) (float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(*env) (float64, error) {
+	return float64(n), nil
+}
+
+type metricRef struct {
+	label string
+	tags  map[string]string
+}
+
+func (r *metricRef) eval(e *env) (float64, error) {
+	m, err := e.current(r)
+	if err != nil {
+		return 0, err
+	}
+	return m.Float64()
+}
+
+func (r *metricRef) String() string {
+	if len(r.tags) == 0 {
+		return r.label
+	}
+	keys := make([]string, 0, len(r.tags))
+	for k := range r.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + strconv.Quote(r.tags[k])
+	}
+	return r.label + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// historyNode is rate(), delta() or prev() of a metric.
+type historyNode struct {
+	fn  string
+	ref *metricRef
+}
+
+func (n *historyNode) eval(e *env) (float64, error) {
+	prev, elapsed, err := e.previous(n.ref)
+	if err != nil {
+		return 0, err
+	}
+	pv, err := prev.Float64()
+	if err != nil {
+		return 0, err
+	}
+	if n.fn == "prev" {
+		return pv, nil
+	}
+
+	cur, err := e.current(n.ref)
+	if err != nil {
+		return 0, err
+	}
+	cv, err := cur.Float64()
+	if err != nil {
+		return 0, err
+	}
+	delta := cv - pv
+	if delta < 0 && cur.Type == check.ResultMetricCounter {
+		return 0, fmt.Errorf("counter %s was reset", n.ref)
+	}
+	if n.fn == "delta" {
+		return delta, nil
+	}
+	if elapsed <= 0 {
+		return 0, fmt.Errorf("no time elapsed since previous %s", n.ref)
+	}
+	return delta / elapsed, nil
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) eval(e *env) (float64, error) {
+	x, err := n.x.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "!" {
+		return boolFloat(x == 0), nil
+	}
+	return -x, nil
+}
+
+type binaryNode struct {
+	op   string
+	x, y node
+}
+
+func (n *binaryNode) eval(e *env) (float64, error) {
+	x, err := n.x.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	// short-circuit logic so that a guard like "total > 0 && used / total > 0.9" never divides by zero
+	switch {
+	case n.op == "&&" && x == 0:
+		return 0, nil
+	case n.op == "||" && x != 0:
+		return 1, nil
+	}
+	y, err := n.y.eval(e)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return x / y, nil
+	case "%":
+		if y == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return math.Mod(x, y), nil
+	case "<":
+		return boolFloat(x < y), nil
+	case "<=":
+		return boolFloat(x <= y), nil
+	case ">":
+		return boolFloat(x > y), nil
+	case ">=":
+		return boolFloat(x >= y), nil
+	case "==":
+		return boolFloat(x == y), nil
+	case "!=":
+		return boolFloat(x != y), nil
+	default: // && and ||
+		return boolFloat(y != 0), nil
+	}
+}
+
+type condNode struct {
+	cond, then, els node
+}
+
+func (n *condNode) eval(e *env) (float64, error) {
+	c, err := n.cond.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	if c != 0 {
+		return n.then.eval(e)
+	}
+	return n.els.eval(e)
+}
+
+type callNode struct {
+	fn   string
+	args []node
+}
+
+func (n *callNode) eval(e *env) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(e)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+
+	switch n.fn {
+	case "min":
+		return minFloat(args), nil
+	case "max":
+		return maxFloat(args), nil
+	default: // abs
+		return math.Abs(args[0]), nil
+	}
+}
+
+func minFloat(values []float64) float64 {
+	m := values[0]
+	for _, v := range values[1:] {
+		m = math.Min(m, v)
+	}
+	return m
+}
+
+func maxFloat(values []float64) float64 {
+	m := values[0]
+	for _, v := range values[1:] {
+		m = math.Max(m, v)
+	}
+	return m
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type tokenKind uint8
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+// twoCharOps are the operators that are two characters long.
+var twoCharOps = []string{"<=", ">=", "==", "!=", "&&", "||"}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for l.pos < len(l.src) && isNumberChar(l.src, l.pos) {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos], pos: start}, nil
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{}, fmt.Errorf("unterminated string at %d", start)
+		}
+		l.pos++
+		s, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return token{}, fmt.Errorf("invalid string at %d: %w", start, err)
+		}
+		return token{kind: tokString, text: s, pos: start}, nil
+	}
+
+	for _, op := range twoCharOps {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += 2
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	if strings.IndexByte("+-*/%<>!?:(),{}=", c) >= 0 {
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}, nil
+	}
+	return token{}, fmt.Errorf("unexpected character %q at %d", c, start)
+}
+
+func isNumberChar(s string, i int) bool {
+	c := s[i]
+	if c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E' {
+		return true
+	}
+	// exponent sign
+	return (c == '+' || c == '-') && i > 0 && (s[i-1] == 'e' || s[i-1] == 'E')
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c == ':' || c >= '0' && c <= '9' || unicode.IsLetter(rune(c))
+}
+
+type parser struct {
+	lexer lexer
+	tok   token
+	err   error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+	if p.err != nil {
+		p.tok = token{kind: tokEOF, pos: p.lexer.pos}
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("%s at %d", fmt.Sprintf(format, args...), p.tok.pos)
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	if p.tok.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q", op)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseExpr() (node, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil || !p.isOp("?") {
+		return cond, err
+	}
+	p.next()
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond: cond, then: then, els: els}, nil
+}
+
+// binaryPrecedence lists binary operators from lowest to highest precedence.
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+	x, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(binaryPrecedence[level]...) {
+		op := p.tok.text
+		p.next()
+		y, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("-", "!") {
+		op := p.tok.text
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	switch {
+	case p.tok.kind == tokNumber:
+		v, err := strconv.ParseFloat(p.tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", p.tok.text)
+		}
+		p.next()
+		return numberNode(v), nil
+	case p.isOp("("):
+		p.next()
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case p.tok.kind == tokIdent:
+		name := p.tok.text
+		p.next()
+		if p.isOp("(") {
+			return p.parseCall(name)
+		}
+		return p.parseMetricRef(name)
+	default:
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+}
+
+func (p *parser) parseMetricRef(label string) (*metricRef, error) {
+	ref := &metricRef{label: label}
+	if !p.isOp("{") {
+		return ref, nil
+	}
+	p.next()
+	ref.tags = make(map[string]string)
+	for !p.isOp("}") {
+		if len(ref.tags) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		if p.tok.kind != tokIdent {
+			return nil, p.errorf("expected tag name")
+		}
+		key := p.tok.text
+		p.next()
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokString {
+			return nil, p.errorf("expected quoted tag value")
+		}
+		ref.tags[key] = p.tok.text
+		p.next()
+	}
+	p.next()
+	return ref, nil
+}
+
+func (p *parser) parseCall(fn string) (node, error) {
+	p.next()
+	var args []node
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+
+	switch fn {
+	case "rate", "delta", "prev":
+		if len(args) != 1 {
+			return nil, p.errorf("%s() takes 1 argument", fn)
+		}
+		ref, ok := args[0].(*metricRef)
+		if !ok {
+			return nil, p.errorf("%s() argument must be a metric", fn)
+		}
+		return &historyNode{fn: fn, ref: ref}, nil
+	case "min", "max":
+		if len(args) == 0 {
+			return nil, p.errorf("%s() takes at least 1 argument", fn)
+		}
+	case "abs":
+		if len(args) != 1 {
+			return nil, p.errorf("abs() takes 1 argument")
+		}
+	case "if":
+		if len(args) != 3 {
+			return nil, p.errorf("if() takes 3 arguments")
+		}
+		return &condNode{cond: args[0], then: args[1], els: args[2]}, nil
+	default:
+		return nil, p.errorf("unknown function %s()", fn)
+	}
+	return &callNode{fn: fn, args: args}, nil
+}
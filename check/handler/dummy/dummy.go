@@ -5,6 +5,10 @@ import (
 	"github.com/seankndy/gopoller/check"
 )
 
+func init() {
+	check.RegisterHandler("dummy", func() check.Handler { return &Handler{} })
+}
+
 type Handler struct{}
 
 func (h *Handler) Mutate(check *check.Check, result *check.Result, newIncident *check.Incident) {
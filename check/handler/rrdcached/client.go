@@ -2,6 +2,7 @@ package rrdcached
 
 import (
 	"fmt"
+	"github.com/seankndy/gopoller/check"
 	"regexp"
 	"strings"
 	"time"
@@ -20,8 +21,17 @@ type Client interface {
 	Create(filename string, ds []DS, rra []RRA, step time.Duration) error
 }
 
+// DefaultClientDialer is the ClientDialer used when neither a Handler nor its
+// Check's check.Environment provides one.
+//
+// Deprecated: provide a ClientDialer with WithClientDialer instead.
 var DefaultClientDialer = new(GoRrdDialer)
 
+// WithClientDialer provides dialer to the Handlers run in a check.Environment.
+func WithClientDialer(dialer ClientDialer) check.EnvOption {
+	return check.WithDependency[ClientDialer](dialer)
+}
+
 // Cmd defines an RRDCacheD command
 type Cmd struct {
 	cmd  string
@@ -1,19 +1,27 @@
 package rrdcached
 
 import (
+	"context"
 	"fmt"
 	"github.com/seankndy/gopoller/check"
 	"strings"
 	"time"
 )
 
+func init() {
+	check.RegisterHandler("rrdcached", func() check.Handler { return NewHandler("", nil) })
+}
+
 // Handler processes check result metrics and sends them to a rrdcached server.
+//
+// GetRrdFileDefs is a func and so is not encoded to JSON.  A Handler decoded from JSON does nothing until it is set
+// again.
 type Handler struct {
 	Addr string
 
 	// GetRrdFileDefs should return a slice of RrdFileDefs defining the RRD file specifications for a given Check and
 	// it's Result data.
-	GetRrdFileDefs func(*check.Check, *check.Result) []RrdFileDef
+	GetRrdFileDefs func(*check.Check, *check.Result) []RrdFileDef `json:"-"`
 
 	clientDialer ClientDialer
 }
@@ -22,7 +30,6 @@ func NewHandler(addr string, getRrdFileDefs func(*check.Check, *check.Result) []
 	return &Handler{
 		Addr:           addr,
 		GetRrdFileDefs: getRrdFileDefs,
-		clientDialer:   DefaultClientDialer,
 	}
 }
 
@@ -30,11 +37,27 @@ func (h *Handler) SetClientDialer(dialer ClientDialer) {
 	h.clientDialer = dialer
 }
 
+// dialer returns the Handler's ClientDialer, else the one provided by the
+// Check's Environment, else DefaultClientDialer.
+func (h *Handler) dialer(chk *check.Check) ClientDialer {
+	if h.clientDialer != nil {
+		return h.clientDialer
+	}
+	if dialer, ok := check.Dependency[ClientDialer](chk.Environment()); ok {
+		return dialer
+	}
+	return DefaultClientDialer
+}
+
 func (h *Handler) Mutate(*check.Check, *check.Result, *check.Incident) {
 	return
 }
 
-func (h *Handler) Process(chk *check.Check, result *check.Result, _ *check.Incident) (err error) {
+func (h *Handler) Process(chk *check.Check, result *check.Result, newIncident *check.Incident) error {
+	return h.ProcessContext(context.Background(), chk, result, newIncident)
+}
+
+func (h *Handler) ProcessContext(ctx context.Context, chk *check.Check, result *check.Result, _ *check.Incident) (err error) {
 	getRrdFileDefs := h.GetRrdFileDefs
 	if getRrdFileDefs == nil {
 		chk.Debugf("no rrd file def func defined")
@@ -46,12 +69,27 @@ func (h *Handler) Process(chk *check.Check, result *check.Result, _ *check.Incid
 		return
 	}
 
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
 	// connect to RRDCacheD
-	client, err := h.clientDialer.Dial(h.Addr)
+	client, err := h.dialer(chk).Dial(h.Addr)
 	if err != nil {
 		return fmt.Errorf("error connecting to rrdcached: %v", err)
 	}
+	// closing the client upon cancellation aborts whatever command is in-flight
+	stopCtxWatch := context.AfterFunc(ctx, func() {
+		client.Close()
+	})
 	defer func() {
+		if !stopCtxWatch() {
+			// ctx is done and the client was (or is being) closed already
+			if err == nil {
+				err = ctx.Err()
+			}
+			return
+		}
 		errC := client.Close()
 		if errC != nil && err == nil {
 			err = fmt.Errorf("error closing connection to rrdcached: %v", errC)
@@ -101,7 +139,8 @@ func (h *Handler) Process(chk *check.Check, result *check.Result, _ *check.Incid
 	return
 }
 
-// RrdFileDef defines a rrd file and it's characteristics
+// RrdFileDef defines a rrd file and it's characteristics.  Each update writes a value for every one of the file's
+// DataSources; a data source whose metric is missing from the Result or is not numeric is written as U (unknown).
 type RrdFileDef struct {
 	Filename           string
 	DataSources        []DS
@@ -111,6 +150,9 @@ type RrdFileDef struct {
 	// Optional metric label to data source name mapping.  By default, metric labels will map to DS names identically.
 	// Use this if your metric name from the check command is different from your DS name.
 	DataSourceToMetricMappings map[string]string
+
+	// Optional tags a metric must have to be written to this file (ex. ifIndex=3 for a per-interface rrd file).
+	MetricTags map[string]string
 }
 
 func buildUpdateCommands(rrdFileDefs []RrdFileDef, result *check.Result) []*Cmd {
@@ -127,16 +169,14 @@ func buildUpdateCommands(rrdFileDefs []RrdFileDef, result *check.Result) []*Cmd
 				}
 			}
 
-			var metric *check.ResultMetric
-			for _, m := range result.Metrics {
-				if m.Label == metricLabel {
-					metric = &m
-					break
+			// rrdtool takes U for unknown values, which keeps the remaining values aligned with their data sources
+			value := "U"
+			if metric := result.Metric(metricLabel, rrdFile.MetricTags); metric != nil {
+				if _, err := metric.Float64(); err == nil {
+					value = metric.Value
 				}
 			}
-			if metric != nil {
-				dsValues = append(dsValues, metric.Value)
-			}
+			dsValues = append(dsValues, value)
 		}
 
 		updateCmds = append(updateCmds, NewCmd("update").WithArgs(
@@ -169,6 +169,45 @@ func TestIssuesCorrectBatchUpdateCommands(t *testing.T) {
 	}
 }
 
+func TestBatchUpdateCommandsSelectMetricsByTagsAndFillUnknowns(t *testing.T) {
+	mockRrdClient := &MockRrdClient{}
+	mockRrdClientDialer := &MockRrdClientDialer{Client: mockRrdClient}
+	h := NewHandler("", func(*check.Check, *check.Result) []RrdFileDef {
+		return []RrdFileDef{
+			{
+				Filename: "/if3.rrd",
+				DataSources: []DS{
+					NewCounterDS("ifHCInOctets", 600, "U", "U"),
+					NewCounterDS("ifHCOutOctets", 600, "U", "U"),
+					NewGaugeDS("ifOperStatus", 600, "U", "U"),
+				},
+				MetricTags: map[string]string{"ifIndex": "3"},
+			},
+		}
+	})
+	h.SetClientDialer(mockRrdClientDialer)
+
+	tm := time.Unix(556549200, 0)
+	result := &check.Result{
+		Metrics: []check.ResultMetric{
+			{Label: "ifHCInOctets", Value: "100", Tags: map[string]string{"ifIndex": "2"}},
+			{Label: "ifHCInOctets", Value: "300", Tags: map[string]string{"ifIndex": "3"}},
+			{Label: "ifHCOutOctets", Value: "not a number", Tags: map[string]string{"ifIndex": "3"}},
+		},
+		Time: tm,
+	}
+
+	_ = h.Process(&check.Check{}, result, nil)
+
+	if mockRrdClient.BatchCalled == 0 {
+		t.Fatal("Batch never called on RRD client")
+	}
+	want := "update /if3.rrd 556549200:300:U:U\n"
+	if got := mockRrdClient.BatchCmds[0][0].String(); got != want {
+		t.Errorf("Bad update command, wanted %q, got %q", want, got)
+	}
+}
+
 type MockRrdClientDialer struct {
 	Client     Client
 	DialCalled int
@@ -222,3 +261,26 @@ func (m *MockRrdClient) Create(filename string, ds []DS, rra []RRA, step time.Du
 
 	return nil
 }
+
+func TestProcessDoesNothingAfterDecodingWithoutGetRrdFileDefs(t *testing.T) {
+	data, err := check.MarshalHandler(NewHandler("localhost:42217", func(*check.Check, *check.Result) []RrdFileDef {
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("MarshalHandler(): unexpected error: %v", err)
+	}
+	h, err := check.UnmarshalHandler(data)
+	if err != nil {
+		t.Fatalf("UnmarshalHandler(): unexpected error: %v", err)
+	}
+
+	mockRrdClientDialer := &MockRrdClientDialer{Client: &MockRrdClient{}}
+	h.(*Handler).SetClientDialer(mockRrdClientDialer)
+
+	if err := h.Process(&check.Check{}, check.NewResult(check.StateOk, "", nil), nil); err != nil {
+		t.Errorf("Process(): unexpected error: %v", err)
+	}
+	if mockRrdClientDialer.DialCalled > 0 {
+		t.Error("Process() connected/dialed to rrdcached unexpectedly")
+	}
+}
@@ -1,33 +1,72 @@
 package statsd
 
 import (
+	"context"
 	"fmt"
 	"github.com/seankndy/gopoller/check"
 	"net"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
+func init() {
+	check.RegisterHandler("statsd", func() check.Handler { return &Handler{} })
+}
+
+// TagStyle is how a Handler sends metric tags to statsd.
+type TagStyle uint8
+
+const (
+	// TagsInPath appends each tag to the metric path as ".key_value", sorted
+	// by key.  This works with any statsd server.  Characters in tags that
+	// would add path segments or break the statsd line (ex. "." and ":") are
+	// replaced with "_".
+	TagsInPath TagStyle = 0
+	// TagsDogStatsD sends tags using the DogStatsD "|#key:value" extension.
+	// Characters in tags that would break the statsd line (ex. "," and ":")
+	// are replaced with "_".
+	TagsDogStatsD TagStyle = 1
+)
+
+var (
+	// lineEscaper replaces the characters that delimit the parts of a statsd line in metric paths.
+	lineEscaper = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+
+	// pathTagEscaper also replaces the characters that would split a tag in the metric path into extra segments.
+	pathTagEscaper = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_", ".", "_", "/", "_")
+)
+
+// Handler sends check result metrics to a statsd server, dialing it with the Check's check.Environment.
+//
+// MetricPrefix is a func and so is not encoded to JSON.  A Handler decoded from JSON sends metrics without a prefix
+// until it is set again.
 type Handler struct {
 	// Addr is the address of statsd server.
 	Addr string
 	// Port is the UDP port number of statsd server.
 	Port uint16
 	// MetricPrefix defines the statsd path prefix for a given Check and Result (default "")
-	MetricPrefix func(*check.Check, *check.Result) string
+	MetricPrefix func(*check.Check, *check.Result) string `json:"-"`
+	// TagStyle is how metric tags are sent (default TagsInPath)
+	TagStyle TagStyle
 }
 
 func (h *Handler) Mutate(*check.Check, *check.Result, *check.Incident) {
 	return
 }
 
-func (h *Handler) Process(chk *check.Check, newResult *check.Result, _ *check.Incident) (err error) {
+func (h *Handler) Process(chk *check.Check, newResult *check.Result, newIncident *check.Incident) error {
+	return h.ProcessContext(context.Background(), chk, newResult, newIncident)
+}
+
+func (h *Handler) ProcessContext(ctx context.Context, chk *check.Check, newResult *check.Result, _ *check.Incident) (err error) {
 	if newResult.Metrics == nil {
 		return
 	}
 
-	dialer := net.Dialer{Timeout: 10 * time.Second}
-	conn, err := dialer.Dial("udp", fmt.Sprintf("%s:%d", h.Addr, h.Port))
+	conn, err := chk.Environment().DialContext(ctx, "udp", net.JoinHostPort(h.Addr, strconv.Itoa(int(h.Port))), 10*time.Second)
 	if err != nil {
 		return
 	}
@@ -35,7 +74,11 @@ func (h *Handler) Process(chk *check.Check, newResult *check.Result, _ *check.In
 		err = conn.Close()
 	}()
 
-	err = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	deadline := time.Now().Add(10 * time.Second)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	err = conn.SetWriteDeadline(deadline)
 	if err != nil {
 		return
 	}
@@ -57,20 +100,42 @@ func (h *Handler) Process(chk *check.Check, newResult *check.Result, _ *check.In
 func (h *Handler) buildProtocolMessage(chk *check.Check, result *check.Result) string {
 	var metricPrefix string
 	if h.MetricPrefix != nil {
-		metricPrefix = strings.TrimRight(strings.ToLower(h.MetricPrefix(chk, result)), ".")
+		metricPrefix = lineEscaper.Replace(strings.TrimRight(strings.ToLower(h.MetricPrefix(chk, result)), "."))
 	}
 
 	var msg strings.Builder
 	for _, metric := range result.Metrics {
-		if metric.Value == "" {
+		value, err := metric.Float64()
+		if err != nil { // not a number statsd could take
 			continue
 		}
 
-		if metric.Value[:1] == "-" { // negative number
+		path, suffix := metricPrefix+"."+lineEscaper.Replace(metric.Label), ""
+		if len(metric.Tags) > 0 {
+			keys := make([]string, 0, len(metric.Tags))
+			for k := range metric.Tags {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			if h.TagStyle == TagsDogStatsD {
+				tags := make([]string, len(keys))
+				for i, k := range keys {
+					tags[i] = lineEscaper.Replace(k) + ":" + lineEscaper.Replace(metric.Tags[k])
+				}
+				suffix = "|#" + strings.Join(tags, ",")
+			} else {
+				for _, k := range keys {
+					path += "." + pathTagEscaper.Replace(k) + "_" + pathTagEscaper.Replace(metric.Tags[k])
+				}
+			}
+		}
+
+		if value < 0 {
 			// see https://github.com/statsd/statsd/blob/master/docs/metric_types.md#gauges
-			msg.WriteString(fmt.Sprintf("%s.%s:0|g\n", metricPrefix, metric.Label))
+			msg.WriteString(fmt.Sprintf("%s:0|g%s\n", path, suffix))
 		}
-		msg.WriteString(fmt.Sprintf("%s.%s:%s|g\n", metricPrefix, metric.Label, metric.Value))
+		msg.WriteString(fmt.Sprintf("%s:%s|g%s\n", path, metric.Value, suffix))
 	}
 	return msg.String()
 }
@@ -0,0 +1,80 @@
+package statsd
+
+import (
+	"context"
+	"github.com/seankndy/gopoller/check"
+	"io"
+	"net"
+	"testing"
+)
+
+// pipeDialer dials one end of a net.Pipe, recording the address dialed.
+type pipeDialer struct {
+	network, address string
+	conn             net.Conn
+}
+
+func (d *pipeDialer) DialContext(_ context.Context, network, address string) (net.Conn, error) {
+	d.network, d.address = network, address
+	return d.conn, nil
+}
+
+func TestProcessDialsWithEnvironmentDialer(t *testing.T) {
+	client, server := net.Pipe()
+	dialer := &pipeDialer{conn: client}
+	chk := check.New("check-1", check.WithEnvironment(check.NewEnvironment(check.WithDialer(dialer))))
+	h := &Handler{Addr: "192.0.2.10", Port: 8125}
+
+	received := make(chan string, 1)
+	go func() {
+		b, _ := io.ReadAll(server)
+		received <- string(b)
+	}()
+
+	result := check.NewResult(check.StateOk, "", []check.ResultMetric{check.NewGaugeMetric("avg", 1.5, check.ResultMetricUnitNone)})
+	if err := h.Process(chk, result, nil); err != nil {
+		t.Fatalf("Process(): unexpected error: %v", err)
+	}
+	if dialer.network != "udp" || dialer.address != "192.0.2.10:8125" {
+		t.Errorf("Process(): expected a udp dial to 192.0.2.10:8125, got %s %s", dialer.network, dialer.address)
+	}
+	if msg := <-received; msg != ".avg:1.5|g\n" {
+		t.Errorf("Process(): expected to send the metric, sent %q", msg)
+	}
+}
+
+func TestBuildProtocolMessage(t *testing.T) {
+	result := &check.Result{Metrics: []check.ResultMetric{
+		{Label: "avg", Value: "23.45"},
+		{Label: "temp", Value: "-5"},
+		{Label: "status", Value: "up"},
+		{Label: "ifHCInOctets", Value: "300", Tags: map[string]string{"ifIndex": "3", "dir": "in"}},
+		{Label: "drops:v4", Value: "7", Tags: map[string]string{"ifName": "ge-0/0/0.100", "vrf": "a:b|c,d#e"}},
+	}}
+	prefix := func(*check.Check, *check.Result) string { return "Router1." }
+
+	tests := []struct {
+		name    string
+		handler *Handler
+		want    string
+	}{
+		{
+			name:    "tags in path",
+			handler: &Handler{MetricPrefix: prefix},
+			want: "router1.avg:23.45|g\nrouter1.temp:0|g\nrouter1.temp:-5|g\nrouter1.ifHCInOctets.dir_in.ifIndex_3:300|g\n" +
+				"router1.drops_v4.ifName_ge-0_0_0_100.vrf_a_b_c_d_e:7|g\n",
+		},
+		{
+			name:    "dogstatsd tags",
+			handler: &Handler{MetricPrefix: prefix, TagStyle: TagsDogStatsD},
+			want: "router1.avg:23.45|g\nrouter1.temp:0|g\nrouter1.temp:-5|g\nrouter1.ifHCInOctets:300|g|#dir:in,ifIndex:3\n" +
+				"router1.drops_v4:7|g|#ifName:ge-0/0/0.100,vrf:a_b_c_d_e\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.handler.buildProtocolMessage(&check.Check{}, result); got != tt.want {
+			t.Errorf("%s: buildProtocolMessage() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
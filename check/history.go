@@ -0,0 +1,249 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DefaultResultHistorySize is the number of Results a ResultHistory keeps
+// when its Size is not set.
+const DefaultResultHistorySize = 100
+
+// ErrInsufficientHistory is returned by ResultHistory helpers when there are
+// not enough samples of a metric to compute a value.
+var ErrInsufficientHistory = errors.New("insufficient result history")
+
+// ResultHistory is a bounded ring buffer of a Check's most recent Results.
+// It keeps at most Size Results, and when MaxAge is set, drops Results older
+// than MaxAge relative to the newest one.
+//
+// Check.Execute() adds each Result after the Check's Handlers have run, so
+// while a Command or Handler runs, the history holds only previous Results.
+type ResultHistory struct {
+	// Size is the most Results kept.
+	Size int
+
+	// MaxAge is how old a Result may be relative to the newest before it is
+	// dropped.  Zero keeps Results regardless of age.
+	MaxAge time.Duration
+
+	buf   []*Result
+	start int
+	len   int
+}
+
+// NewResultHistory creates a ResultHistory that keeps at most size Results no
+// older than maxAge.
+func NewResultHistory(size int, maxAge time.Duration) *ResultHistory {
+	return &ResultHistory{Size: size, MaxAge: maxAge}
+}
+
+func (h *ResultHistory) size() int {
+	if h.Size <= 0 {
+		return DefaultResultHistorySize
+	}
+	return h.Size
+}
+
+// Add appends result as the newest Result, dropping the oldest as needed.
+func (h *ResultHistory) Add(result *Result) {
+	if size := h.size(); len(h.buf) != size {
+		h.resize(size)
+	}
+
+	if h.len < len(h.buf) {
+		h.buf[(h.start+h.len)%len(h.buf)] = result
+		h.len++
+	} else {
+		h.buf[h.start] = result
+		h.start = (h.start + 1) % len(h.buf)
+	}
+
+	if h.MaxAge > 0 {
+		for h.len > 1 && result.Time.Sub(h.At(0).Time) > h.MaxAge {
+			h.buf[h.start] = nil
+			h.start = (h.start + 1) % len(h.buf)
+			h.len--
+		}
+	}
+}
+
+// clone returns a copy of the history that does not share its buffer.
+func (h *ResultHistory) clone() *ResultHistory {
+	c := *h
+	c.buf = append([]*Result(nil), h.buf...)
+	return &c
+}
+
+// resize reallocates the buffer to size, keeping the newest Results.
+func (h *ResultHistory) resize(size int) {
+	results := h.Results()
+	if len(results) > size {
+		results = results[len(results)-size:]
+	}
+	h.buf = make([]*Result, size)
+	h.start = 0
+	h.len = copy(h.buf, results)
+}
+
+// Len returns the number of Results in the history.
+func (h *ResultHistory) Len() int {
+	return h.len
+}
+
+// At returns the i-th Result, oldest first.  It panics if i is out of range.
+func (h *ResultHistory) At(i int) *Result {
+	if i < 0 || i >= h.len {
+		panic("check: ResultHistory index out of range")
+	}
+	return h.buf[(h.start+i)%len(h.buf)]
+}
+
+// Latest returns the newest Result, or nil if the history is empty.
+func (h *ResultHistory) Latest() *Result {
+	if h.len == 0 {
+		return nil
+	}
+	return h.At(h.len - 1)
+}
+
+// Results returns a copy of the Results, oldest first.
+func (h *ResultHistory) Results() []*Result {
+	results := make([]*Result, h.len)
+	for i := range results {
+		results[i] = h.At(i)
+	}
+	return results
+}
+
+// PreviousMetric returns the newest metric with the given label and tags along
+// with the time of the Result it belongs to, or nil if no Result has it.
+func (h *ResultHistory) PreviousMetric(label string, tags map[string]string) (*ResultMetric, time.Time) {
+	for i := h.len - 1; i >= 0; i-- {
+		if m := h.At(i).Metric(label, tags); m != nil {
+			return m, h.At(i).Time
+		}
+	}
+	return nil, time.Time{}
+}
+
+// metricSample is a numeric metric value at a point in time.
+type metricSample struct {
+	value      float64
+	time       time.Time
+	metricType ResultMetricType
+}
+
+// samples returns the numeric values of the metric, oldest first, from
+// Results no older than window relative to the newest Result.  A window <= 0
+// includes the entire history.
+func (h *ResultHistory) samples(label string, tags map[string]string, window time.Duration) []metricSample {
+	latest := h.Latest()
+	if latest == nil {
+		return nil
+	}
+
+	var samples []metricSample
+	for i := 0; i < h.len; i++ {
+		r := h.At(i)
+		if window > 0 && latest.Time.Sub(r.Time) > window {
+			continue
+		}
+		m := r.Metric(label, tags)
+		if m == nil {
+			continue
+		}
+		if v, err := m.Float64(); err == nil {
+			samples = append(samples, metricSample{value: v, time: r.Time, metricType: m.Type})
+		}
+	}
+	return samples
+}
+
+// Rate returns the per-second rate of change of a metric over window (or the
+// entire history if window <= 0).  Counter metrics sum only increases so that
+// a counter reset does not produce a negative rate.
+func (h *ResultHistory) Rate(label string, tags map[string]string, window time.Duration) (float64, error) {
+	samples := h.samples(label, tags, window)
+	if len(samples) < 2 {
+		return 0, ErrInsufficientHistory
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	elapsed := last.time.Sub(first.time).Seconds()
+	if elapsed <= 0 {
+		return 0, ErrInsufficientHistory
+	}
+
+	if last.metricType != ResultMetricCounter {
+		return (last.value - first.value) / elapsed, nil
+	}
+	var delta float64
+	for i := 1; i < len(samples); i++ {
+		if d := samples[i].value - samples[i-1].value; d > 0 {
+			delta += d
+		}
+	}
+	return delta / elapsed, nil
+}
+
+// MetricStats summarizes the values of a metric over a window of history.
+type MetricStats struct {
+	Count int
+	Min   float64
+	Max   float64
+	Avg   float64
+}
+
+// Stats returns the minimum, maximum and average of a metric over window (or
+// the entire history if window <= 0).
+func (h *ResultHistory) Stats(label string, tags map[string]string, window time.Duration) (MetricStats, error) {
+	samples := h.samples(label, tags, window)
+	if len(samples) == 0 {
+		return MetricStats{}, ErrInsufficientHistory
+	}
+
+	stats := MetricStats{Count: len(samples), Min: samples[0].value, Max: samples[0].value}
+	var sum float64
+	for _, s := range samples {
+		stats.Min = min(stats.Min, s.value)
+		stats.Max = max(stats.Max, s.value)
+		sum += s.value
+	}
+	stats.Avg = sum / float64(len(samples))
+	return stats, nil
+}
+
+// ConsecutiveCount returns how many of the newest Results in a row have the
+// given state.
+func (h *ResultHistory) ConsecutiveCount(state ResultState) int {
+	n := 0
+	for i := h.len - 1; i >= 0 && h.At(i).State == state; i-- {
+		n++
+	}
+	return n
+}
+
+// resultHistoryJSON is the encoded form of a ResultHistory.
+type resultHistoryJSON struct {
+	Size    int           `json:"size,omitempty"`
+	MaxAge  time.Duration `json:"maxAge,omitempty"`
+	Results []*Result     `json:"results,omitempty"`
+}
+
+func (h *ResultHistory) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resultHistoryJSON{Size: h.Size, MaxAge: h.MaxAge, Results: h.Results()})
+}
+
+func (h *ResultHistory) UnmarshalJSON(data []byte) error {
+	var j resultHistoryJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*h = ResultHistory{Size: j.Size, MaxAge: j.MaxAge}
+	for _, r := range j.Results {
+		h.Add(r)
+	}
+	return nil
+}
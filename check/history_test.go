@@ -0,0 +1,165 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func historyResult(state ResultState, t time.Time, metrics ...ResultMetric) *Result {
+	r := NewResult(state, "", metrics)
+	r.Time = t
+	return r
+}
+
+func TestResultHistory_AddDropsOldestWhenFull(t *testing.T) {
+	h := NewResultHistory(3, 0)
+	base := time.Unix(1000, 0)
+	for i := 0; i < 5; i++ {
+		h.Add(historyResult(StateOk, base.Add(time.Duration(i)*time.Second)))
+	}
+
+	if h.Len() != 3 {
+		t.Fatalf("Len(): expected 3, got %d", h.Len())
+	}
+	for i, r := range h.Results() {
+		if want := base.Add(time.Duration(i+2) * time.Second); !r.Time.Equal(want) {
+			t.Errorf("Results()[%d]: expected time %v, got %v", i, want, r.Time)
+		}
+	}
+}
+
+func TestResultHistory_AddDropsResultsOlderThanMaxAge(t *testing.T) {
+	h := NewResultHistory(10, time.Minute)
+	base := time.Unix(1000, 0)
+	h.Add(historyResult(StateOk, base))
+	h.Add(historyResult(StateOk, base.Add(30*time.Second)))
+	h.Add(historyResult(StateOk, base.Add(90*time.Second)))
+
+	if h.Len() != 2 || !h.At(0).Time.Equal(base.Add(30*time.Second)) {
+		t.Errorf("Add(): expected oldest result to be dropped, got %d results starting at %v", h.Len(), h.At(0).Time)
+	}
+}
+
+func TestResultHistory_PreviousMetricSkipsResultsWithoutIt(t *testing.T) {
+	h := NewResultHistory(10, 0)
+	base := time.Unix(1000, 0)
+	h.Add(historyResult(StateOk, base, NewCounterMetric("octets", 100, ResultMetricUnitOctets)))
+	h.Add(historyResult(StateUnknown, base.Add(time.Minute)))
+
+	m, tm := h.PreviousMetric("octets", nil)
+	if m == nil || m.Value != "100" || !tm.Equal(base) {
+		t.Errorf("PreviousMetric(): expected octets=100 at %v, got %v at %v", base, m, tm)
+	}
+	if m, _ := h.PreviousMetric("missing", nil); m != nil {
+		t.Errorf("PreviousMetric(): expected nil for missing metric, got %v", m)
+	}
+}
+
+func TestResultHistory_RateIgnoresCounterResets(t *testing.T) {
+	h := NewResultHistory(10, 0)
+	base := time.Unix(1000, 0)
+	for i, v := range []uint64{100, 700, 50, 350} {
+		h.Add(historyResult(StateOk, base.Add(time.Duration(i)*time.Minute), NewCounterMetric("octets", v, ResultMetricUnitOctets)))
+	}
+
+	// increases of 600 and 300 over 180 seconds, skipping the reset
+	rate, err := h.Rate("octets", nil, 0)
+	if err != nil || rate != 5 {
+		t.Errorf("Rate(): expected 5, got %v (%v)", rate, err)
+	}
+
+	rate, err = h.Rate("octets", nil, time.Minute)
+	if err != nil || rate != 5 {
+		t.Errorf("Rate() over 1m: expected 5, got %v (%v)", rate, err)
+	}
+}
+
+func TestResultHistory_RateOfGauge(t *testing.T) {
+	h := NewResultHistory(10, 0)
+	base := time.Unix(1000, 0)
+	h.Add(historyResult(StateOk, base, NewGaugeMetric("temp", 30, ResultMetricUnitNone)))
+	h.Add(historyResult(StateOk, base.Add(10*time.Second), NewGaugeMetric("temp", 25, ResultMetricUnitNone)))
+
+	if rate, err := h.Rate("temp", nil, 0); err != nil || rate != -0.5 {
+		t.Errorf("Rate(): expected -0.5, got %v (%v)", rate, err)
+	}
+}
+
+func TestResultHistory_RateRequiresTwoSamples(t *testing.T) {
+	h := NewResultHistory(10, 0)
+	h.Add(historyResult(StateOk, time.Unix(1000, 0), NewGaugeMetric("temp", 30, ResultMetricUnitNone)))
+
+	if _, err := h.Rate("temp", nil, 0); !errors.Is(err, ErrInsufficientHistory) {
+		t.Errorf("Rate(): expected ErrInsufficientHistory, got %v", err)
+	}
+}
+
+func TestResultHistory_Stats(t *testing.T) {
+	h := NewResultHistory(10, 0)
+	base := time.Unix(1000, 0)
+	for i, v := range []float64{10, 40, 20, 30} {
+		h.Add(historyResult(StateOk, base.Add(time.Duration(i)*time.Minute), NewGaugeMetric("rtt", v, ResultMetricUnitMilliseconds)))
+	}
+
+	stats, err := h.Stats("rtt", nil, 0)
+	if err != nil || stats != (MetricStats{Count: 4, Min: 10, Max: 40, Avg: 25}) {
+		t.Errorf("Stats(): unexpected %+v (%v)", stats, err)
+	}
+	stats, err = h.Stats("rtt", nil, 2*time.Minute)
+	if err != nil || stats != (MetricStats{Count: 3, Min: 20, Max: 40, Avg: 30}) {
+		t.Errorf("Stats() over 2m: unexpected %+v (%v)", stats, err)
+	}
+}
+
+func TestResultHistory_ConsecutiveCount(t *testing.T) {
+	h := NewResultHistory(10, 0)
+	base := time.Unix(1000, 0)
+	for i, s := range []ResultState{StateOk, StateCrit, StateOk, StateOk} {
+		h.Add(historyResult(s, base.Add(time.Duration(i)*time.Minute)))
+	}
+
+	if n := h.ConsecutiveCount(StateOk); n != 2 {
+		t.Errorf("ConsecutiveCount(OK): expected 2, got %d", n)
+	}
+	if n := h.ConsecutiveCount(StateCrit); n != 0 {
+		t.Errorf("ConsecutiveCount(CRIT): expected 0, got %d", n)
+	}
+}
+
+func TestResultHistory_JSONRoundTrip(t *testing.T) {
+	h := NewResultHistory(2, time.Hour)
+	base := time.Unix(1000, 0).UTC()
+	for i := 0; i < 3; i++ {
+		h.Add(historyResult(StateOk, base.Add(time.Duration(i)*time.Minute)))
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("json.Marshal(): unexpected error: %v", err)
+	}
+	var decoded ResultHistory
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal(): unexpected error: %v", err)
+	}
+
+	if decoded.Size != 2 || decoded.MaxAge != time.Hour || decoded.Len() != 2 ||
+		decoded.At(0).Id != h.At(0).Id || decoded.At(1).Id != h.At(1).Id {
+		t.Errorf("json round trip: expected %v, got %v", h.Results(), decoded.Results())
+	}
+}
+
+func TestCheck_ExecuteAddsResultToHistory(t *testing.T) {
+	c := New("check-1", WithCommand(&stateCommand{state: StateWarn}), WithResultHistory(5, 0))
+
+	_ = c.Execute()
+	_ = c.Execute()
+
+	if c.History.Len() != 2 || c.History.Latest() != c.LastResult {
+		t.Errorf("Execute(): expected 2 results in history ending with LastResult, got %d", c.History.Len())
+	}
+	if n := c.History.ConsecutiveCount(StateWarn); n != 2 {
+		t.Errorf("Execute(): expected 2 consecutive WARN results, got %d", n)
+	}
+}
@@ -5,31 +5,204 @@ import (
 	"time"
 )
 
+// IncidentType is the kind of event an Incident represents.
+type IncidentType uint8
+
+const (
+	// IncidentTypeState is an Incident opened by a non-OK state change.
+	IncidentTypeState IncidentType = 0
+	// IncidentTypeFlapping is an Incident opened when a Check starts flapping.
+	// It is resolved when the Check stops flapping.
+	IncidentTypeFlapping IncidentType = 1
+)
+
+func (t IncidentType) String() string {
+	if t == IncidentTypeFlapping {
+		return "FLAPPING"
+	}
+	return "STATE"
+}
+
+// IncidentEventType is the kind of event recorded in an Incident's Timeline.
+type IncidentEventType uint8
+
+const (
+	IncidentOpened         IncidentEventType = 0
+	IncidentEscalated      IncidentEventType = 1
+	IncidentAcknowledged   IncidentEventType = 2
+	IncidentUnacknowledged IncidentEventType = 3
+	IncidentResolved       IncidentEventType = 4
+	IncidentNoted          IncidentEventType = 5
+)
+
+func (t IncidentEventType) String() string {
+	switch t {
+	case IncidentOpened:
+		return "OPENED"
+	case IncidentEscalated:
+		return "ESCALATED"
+	case IncidentAcknowledged:
+		return "ACKNOWLEDGED"
+	case IncidentUnacknowledged:
+		return "UNACKNOWLEDGED"
+	case IncidentResolved:
+		return "RESOLVED"
+	default:
+		return "NOTE"
+	}
+}
+
+// IncidentEvent is an entry in an Incident's Timeline.
+type IncidentEvent struct {
+	Type IncidentEventType `json:"type"`
+	Time time.Time         `json:"time"`
+
+	// State is the Incident's ToState when the event happened.
+	State ResultState `json:"state"`
+
+	Author  string `json:"author,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Acknowledgement is who acknowledged an Incident, why and for how long.
+type Acknowledgement struct {
+	Author  string    `json:"author,omitempty"`
+	Comment string    `json:"comment,omitempty"`
+	Time    time.Time `json:"time"`
+
+	// Expires, when set, is when the acknowledgement lapses on its own.
+	Expires *time.Time `json:"expires,omitempty"`
+
+	// Sticky acknowledgements last until the Incident is resolved.
+	// Non-sticky acknowledgements are removed when the state worsens (ex.
+	// WARN to CRIT).
+	Sticky bool `json:"sticky,omitempty"`
+}
+
+// IsExpired returns true if the acknowledgement has lapsed as of t.
+func (a *Acknowledgement) IsExpired(t time.Time) bool {
+	return a.Expires != nil && !t.Before(*a.Expires)
+}
+
+type AckOption func(*Acknowledgement)
+
+// WithAckExpiry makes the acknowledgement lapse at expires.
+func WithAckExpiry(expires time.Time) AckOption {
+	return func(a *Acknowledgement) {
+		a.Expires = &expires
+	}
+}
+
+// WithStickyAck makes the acknowledgement last until the Incident is resolved.
+func WithStickyAck() AckOption {
+	return func(a *Acknowledgement) {
+		a.Sticky = true
+	}
+}
+
 // Incident defines a Check that has undergone a non-OK state change.
 type Incident struct {
-	Id           uuid.UUID
-	FromState    ResultState
-	ToState      ResultState
-	ReasonCode   string
-	Time         time.Time
-	Resolved     *time.Time
-	Acknowledged *time.Time
+	Id           uuid.UUID    `json:"id"`
+	Type         IncidentType `json:"type"`
+	FromState    ResultState  `json:"fromState"`
+	ToState      ResultState  `json:"toState"`
+	ReasonCode   string       `json:"reasonCode"`
+	Time         time.Time    `json:"time"`
+	Resolved     *time.Time   `json:"resolved,omitempty"`
+	Acknowledged *time.Time   `json:"acknowledged,omitempty"`
+
+	// Acknowledgement is the current acknowledgement, or nil when the
+	// Incident is not acknowledged.  Acknowledged is set to its Time.
+	Acknowledgement *Acknowledgement `json:"acknowledgement,omitempty"`
+
+	// Timeline is the log of what happened to the Incident, oldest first.
+	Timeline []IncidentEvent `json:"timeline,omitempty"`
+
+	// StateChanges is every state the Incident has been in, oldest first,
+	// starting with the state it was opened with.
+	StateChanges []IncidentStateChange `json:"stateChanges,omitempty"`
+
+	// WorstState is the worst state the Incident has reached.
+	WorstState ResultState `json:"worstState"`
+}
+
+// IncidentStateChange is a state an Incident changed to while open.
+type IncidentStateChange struct {
+	State      ResultState `json:"state"`
+	ReasonCode string      `json:"reasonCode"`
+	Time       time.Time   `json:"time"`
 }
 
 // Resolve sets the Incident to resolved at the current time.
+//
+// Resolve, Acknowledge, AcknowledgeBy, Unacknowledge, IsAcknowledged, AddNote
+// and Escalate all use time.Now().  Code working with a Check should use their
+// At variants with the Check's Environment time instead (see
+// Environment.Now), so that the Incident is timed by the same clock as its
+// Check.
 func (i *Incident) Resolve() {
-	t := time.Now()
+	i.ResolveAt(time.Now())
+}
+
+// ResolveAt sets the Incident to resolved at t.
+func (i *Incident) ResolveAt(t time.Time) {
 	i.Resolved = &t
+	i.record(IncidentResolved, t, "", "")
 }
 
 // Acknowledge sets the Incident to acknowledged at the current time.
 func (i *Incident) Acknowledge() {
-	t := time.Now()
-	i.Acknowledged = &t
+	i.AcknowledgeBy("", "")
+}
+
+// AcknowledgeBy acknowledges the Incident at the current time on behalf of
+// author, replacing any existing acknowledgement.
+func (i *Incident) AcknowledgeBy(author, comment string, options ...AckOption) {
+	i.AcknowledgeAt(time.Now(), author, comment, options...)
 }
 
-// IsAcknowledged returns true if incident has been acknowledged.
+// AcknowledgeAt is like AcknowledgeBy at t.
+func (i *Incident) AcknowledgeAt(t time.Time, author, comment string, options ...AckOption) {
+	ack := &Acknowledgement{
+		Author:  author,
+		Comment: comment,
+		Time:    t,
+	}
+	for _, option := range options {
+		option(ack)
+	}
+
+	i.Acknowledgement = ack
+	i.Acknowledged = &ack.Time
+	i.record(IncidentAcknowledged, ack.Time, author, comment)
+}
+
+// Unacknowledge removes the Incident's acknowledgement, if any.
+func (i *Incident) Unacknowledge(author, comment string) {
+	i.UnacknowledgeAt(time.Now(), author, comment)
+}
+
+// UnacknowledgeAt is like Unacknowledge at t.
+func (i *Incident) UnacknowledgeAt(t time.Time, author, comment string) {
+	if i.Acknowledged == nil {
+		return
+	}
+	i.Acknowledgement = nil
+	i.Acknowledged = nil
+	i.record(IncidentUnacknowledged, t, author, comment)
+}
+
+// IsAcknowledged returns true if incident has been acknowledged and the
+// acknowledgement has not expired.
 func (i *Incident) IsAcknowledged() bool {
+	return i.IsAcknowledgedAt(time.Now())
+}
+
+// IsAcknowledgedAt is like IsAcknowledged as of t.
+func (i *Incident) IsAcknowledgedAt(t time.Time) bool {
+	if i.Acknowledgement != nil && i.Acknowledgement.IsExpired(t) {
+		return false
+	}
 	return i.Acknowledged != nil
 }
 
@@ -38,18 +211,111 @@ func (i *Incident) IsResolved() bool {
 	return i.Resolved != nil
 }
 
+// AddNote adds a comment to the Incident's Timeline.
+func (i *Incident) AddNote(author, comment string) {
+	i.AddNoteAt(time.Now(), author, comment)
+}
+
+// AddNoteAt is like AddNote at t.
+func (i *Incident) AddNoteAt(t time.Time, author, comment string) {
+	i.record(IncidentNoted, t, author, comment)
+}
+
+// Escalate changes the Incident's ToState and reason code while it stays
+// open, recording the change in StateChanges.  A non-sticky acknowledgement is
+// removed if the state worsened.
+func (i *Incident) Escalate(state ResultState, reasonCode string) {
+	i.EscalateAt(time.Now(), state, reasonCode)
+}
+
+// EscalateAt is like Escalate at t.
+func (i *Incident) EscalateAt(t time.Time, state ResultState, reasonCode string) {
+	worsened := state.Overrides(i.ToState)
+	i.ToState = state
+	i.ReasonCode = reasonCode
+	i.recordStateChange(t)
+	i.record(IncidentEscalated, t, "", reasonCode)
+
+	if worsened && i.Acknowledgement != nil && !i.Acknowledgement.Sticky {
+		i.UnacknowledgeAt(t, "", "state worsened to "+state.String())
+	}
+}
+
+func (i *Incident) recordStateChange(t time.Time) {
+	i.StateChanges = append(i.StateChanges, IncidentStateChange{State: i.ToState, ReasonCode: i.ReasonCode, Time: t})
+	if len(i.StateChanges) == 1 || i.ToState.Overrides(i.WorstState) {
+		i.WorstState = i.ToState
+	}
+}
+
+func (i *Incident) record(eventType IncidentEventType, t time.Time, author, comment string) {
+	i.Timeline = append(i.Timeline, IncidentEvent{
+		Type:    eventType,
+		Time:    t,
+		State:   i.ToState,
+		Author:  author,
+		Comment: comment,
+	})
+}
+
+// expireAcknowledgement removes the acknowledgement if it has expired as of t.
+func (i *Incident) expireAcknowledgement(t time.Time) {
+	if i.Acknowledgement != nil && i.Acknowledgement.IsExpired(t) {
+		i.UnacknowledgeAt(t, "", "acknowledgement expired")
+	}
+}
+
+// inheritAcknowledgement carries an acknowledgement over from the unresolved
+// Incident this one replaces at t, unless it is non-sticky and the state
+// worsened.
+func (i *Incident) inheritAcknowledgement(previous *Incident, t time.Time) {
+	ack := previous.Acknowledgement
+	if ack == nil || ack.IsExpired(t) || previous.IsResolved() {
+		return
+	}
+	if !ack.Sticky && i.ToState.Overrides(previous.ToState) {
+		return
+	}
+
+	i.Acknowledgement = ack
+	i.Acknowledged = &ack.Time
+	i.record(IncidentAcknowledged, t, ack.Author, ack.Comment)
+}
+
 // MakeIncidentFromResults creates a new Incident based on a Check last Result,
 // and it's current Result.
 func MakeIncidentFromResults(lastResult *Result, currentResult *Result) *Incident {
+	return makeIncidentAt(lastResult, currentResult, time.Now())
+}
+
+func makeIncidentAt(lastResult *Result, currentResult *Result, t time.Time) *Incident {
 	if lastResult == nil {
 		lastResult = MakeUnknownResult("")
 	}
 
-	return &Incident{
+	i := &Incident{
 		Id:         uuid.New(),
 		FromState:  lastResult.State,
 		ToState:    currentResult.State,
 		ReasonCode: currentResult.ReasonCode,
-		Time:       time.Now(),
+		Time:       t,
 	}
+	i.recordStateChange(i.Time)
+	i.record(IncidentOpened, i.Time, "", currentResult.ReasonCode)
+	return i
+}
+
+// MakeFlappingIncident creates a new flapping Incident based on a Check's last
+// Result, and the current Result that caused it to start flapping.
+func MakeFlappingIncident(lastResult *Result, currentResult *Result) *Incident {
+	return makeFlappingIncidentAt(lastResult, currentResult, time.Now())
+}
+
+func makeFlappingIncidentAt(lastResult *Result, currentResult *Result, t time.Time) *Incident {
+	i := makeIncidentAt(lastResult, currentResult, t)
+	i.Type = IncidentTypeFlapping
+	i.ReasonCode = "FLAPPING"
+	i.StateChanges[0].ReasonCode = i.ReasonCode
+	i.Timeline[0].Comment = i.ReasonCode
+	return i
 }
@@ -0,0 +1,208 @@
+package check
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func timelineTypes(i *Incident) []IncidentEventType {
+	var types []IncidentEventType
+	for _, e := range i.Timeline {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestIncident_AcknowledgeByRecordsAuthorAndComment(t *testing.T) {
+	i := MakeIncidentFromResults(nil, NewResult(StateCrit, "DOWN", nil))
+	i.AcknowledgeBy("alice", "looking into it")
+
+	if !i.IsAcknowledged() || i.Acknowledgement.Author != "alice" || i.Acknowledgement.Comment != "looking into it" {
+		t.Errorf("AcknowledgeBy(): unexpected acknowledgement %+v", i.Acknowledgement)
+	}
+	if i.Acknowledged == nil || !i.Acknowledged.Equal(i.Acknowledgement.Time) {
+		t.Error("AcknowledgeBy(): expected Acknowledged to be set to the acknowledgement time")
+	}
+
+	i.AddNote("bob", "ISP ticket opened")
+	i.Unacknowledge("alice", "handing off")
+	i.Resolve()
+
+	want := []IncidentEventType{IncidentOpened, IncidentAcknowledged, IncidentNoted, IncidentUnacknowledged, IncidentResolved}
+	if got := timelineTypes(i); !reflect.DeepEqual(want, got) {
+		t.Errorf("Timeline: expected %v, got %v", want, got)
+	}
+	if i.IsAcknowledged() || i.Acknowledgement != nil {
+		t.Error("Unacknowledge(): expected incident to no longer be acknowledged")
+	}
+}
+
+func TestIncident_AcknowledgementExpires(t *testing.T) {
+	i := MakeIncidentFromResults(nil, NewResult(StateCrit, "DOWN", nil))
+	i.AcknowledgeBy("alice", "", WithAckExpiry(time.Now().Add(-time.Second)))
+
+	if i.IsAcknowledged() {
+		t.Error("IsAcknowledged(): expected expired acknowledgement to not count")
+	}
+}
+
+func TestIncident_EscalateRemovesNonStickyAckWhenWorsening(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   []AckOption
+		from, to  ResultState
+		wantAcked bool
+	}{
+		{"non-sticky worsening", nil, StateWarn, StateCrit, false},
+		{"non-sticky improving", nil, StateCrit, StateWarn, true},
+		{"sticky worsening", []AckOption{WithStickyAck()}, StateWarn, StateCrit, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := MakeIncidentFromResults(nil, NewResult(tt.from, "", nil))
+			i.AcknowledgeBy("alice", "", tt.options...)
+			i.Escalate(tt.to, "")
+
+			if i.IsAcknowledged() != tt.wantAcked {
+				t.Errorf("Escalate(): expected acknowledged=%v, got %v", tt.wantAcked, i.IsAcknowledged())
+			}
+			if i.ToState != tt.to {
+				t.Errorf("Escalate(): expected ToState %s, got %s", tt.to, i.ToState)
+			}
+		})
+	}
+}
+
+func TestCheck_ExecuteCarriesAcknowledgementToReplacingIncident(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   []AckOption
+		from, to  ResultState
+		wantAcked bool
+	}{
+		{"non-sticky worsening", nil, StateWarn, StateCrit, false},
+		{"non-sticky improving", nil, StateCrit, StateWarn, true},
+		{"sticky worsening", []AckOption{WithStickyAck()}, StateWarn, StateCrit, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &stateCommand{state: tt.from}
+			c := New("check-1", WithCommand(cmd))
+			_ = c.Execute()
+			c.Incident.AcknowledgeBy("alice", "on it", tt.options...)
+
+			cmd.state = tt.to
+			_ = c.Execute()
+
+			if c.Incident.ToState != tt.to {
+				t.Fatalf("Execute(): expected new %s incident, got %s", tt.to, c.Incident.ToState)
+			}
+			if c.Incident.IsAcknowledged() != tt.wantAcked {
+				t.Errorf("Execute(): expected acknowledged=%v, got %v", tt.wantAcked, c.Incident.IsAcknowledged())
+			}
+		})
+	}
+}
+
+func TestCheck_ExecuteRemovesExpiredAcknowledgement(t *testing.T) {
+	c := New("check-1", WithCommand(&stateCommand{state: StateCrit}))
+	_ = c.Execute()
+	c.Incident.AcknowledgeBy("alice", "", WithAckExpiry(time.Now().Add(-time.Second)))
+
+	_ = c.Execute()
+
+	if c.Incident.Acknowledgement != nil || c.Incident.Acknowledged != nil {
+		t.Error("Execute(): expected expired acknowledgement to be removed")
+	}
+	last := c.Incident.Timeline[len(c.Incident.Timeline)-1]
+	if last.Type != IncidentUnacknowledged || last.Comment != "acknowledgement expired" {
+		t.Errorf("Execute(): expected expiry in timeline, got %+v", last)
+	}
+}
+
+func TestIncident_JSONRoundTrip(t *testing.T) {
+	i := MakeIncidentFromResults(nil, NewResult(StateWarn, "HIGH_RTT", nil))
+	i.AcknowledgeBy("alice", "known issue", WithStickyAck(), WithAckExpiry(time.Now().Add(time.Hour)))
+	i.Escalate(StateCrit, "DOWN")
+
+	data, err := json.Marshal(i)
+	if err != nil {
+		t.Fatalf("json.Marshal(): unexpected error: %v", err)
+	}
+	var decoded Incident
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal(): unexpected error: %v", err)
+	}
+
+	if !decoded.IsAcknowledged() || !decoded.Acknowledgement.Sticky || decoded.Acknowledgement.Author != "alice" {
+		t.Errorf("json round trip: unexpected acknowledgement %+v", decoded.Acknowledgement)
+	}
+	if !reflect.DeepEqual(timelineTypes(i), timelineTypes(&decoded)) || decoded.Timeline[2].State != StateCrit {
+		t.Errorf("json round trip: unexpected timeline %+v", decoded.Timeline)
+	}
+}
+
+func TestCheck_ExecuteKeepsContinuousIncidentOpenAcrossNonOkStates(t *testing.T) {
+	cmd := &stateCommand{state: StateWarn}
+	c := New("check-1", WithCommand(cmd), WithContinuousIncidents())
+
+	_ = c.Execute()
+	incident := c.Incident
+	for _, state := range []ResultState{StateCrit, StateWarn} {
+		cmd.state = state
+		_ = c.Execute()
+		if c.Incident != incident || incident.IsResolved() {
+			t.Fatalf("Execute(): expected incident to stay open on %s", state)
+		}
+	}
+
+	if incident.ToState != StateWarn || incident.WorstState != StateCrit {
+		t.Errorf("Execute(): expected WARN incident with worst state CRIT, got %s with worst %s", incident.ToState, incident.WorstState)
+	}
+	var states []ResultState
+	for _, change := range incident.StateChanges {
+		states = append(states, change.State)
+	}
+	if want := []ResultState{StateWarn, StateCrit, StateWarn}; !reflect.DeepEqual(want, states) {
+		t.Errorf("Execute(): expected state changes %v, got %v", want, states)
+	}
+
+	cmd.state = StateOk
+	_ = c.Execute()
+	if !incident.IsResolved() {
+		t.Error("Execute(): expected incident to resolve on OK")
+	}
+}
+
+func TestCheck_ExecuteResolvesIncidentAfterRecoveryThreshold(t *testing.T) {
+	cmd := &stateCommand{state: StateCrit}
+	c := New("check-1", WithCommand(cmd), WithRecoveryThreshold(3))
+	_ = c.Execute()
+	incident := c.Incident
+
+	cmd.state = StateOk
+	_ = c.Execute()
+	_ = c.Execute()
+	if incident.IsResolved() {
+		t.Fatal("Execute(): expected incident to stay open before 3 consecutive OK results")
+	}
+
+	// a relapse to the same state continues the incident rather than opening another
+	cmd.state = StateCrit
+	_ = c.Execute()
+	if c.Incident != incident || incident.IsResolved() {
+		t.Fatal("Execute(): expected relapse to continue the open incident")
+	}
+
+	cmd.state = StateOk
+	for i := 0; i < 3; i++ {
+		_ = c.Execute()
+	}
+	if !incident.IsResolved() {
+		t.Error("Execute(): expected incident to resolve after 3 consecutive OK results")
+	}
+}
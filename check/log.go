@@ -0,0 +1,140 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// DebugLogger receives a Check's debug messages when set with WithDebugLogger.
+//
+// Deprecated: use WithLogger and WithDebug instead.
+type DebugLogger interface {
+	Debugf(format string, args ...any)
+}
+
+// WithLogger sets the logger the Check logs to.  See Check.Logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Check) {
+		c.logger = logger
+	}
+}
+
+// WithDebug enables debug logging for the Check.  See Check.SetDebug.
+func WithDebug() Option {
+	return func(c *Check) {
+		c.SetDebug(true)
+	}
+}
+
+// WithDebugLogger sends the Check's debug messages to logger, formatted and
+// prefixed with the Check's ID and the caller, instead of to its slog.Logger.
+//
+// Deprecated: use WithLogger and WithDebug instead.
+func WithDebugLogger(logger DebugLogger) Option {
+	return func(c *Check) {
+		c.debugLogger = logger
+	}
+}
+
+// SetDebugLogger is like WithDebugLogger.
+//
+// Deprecated: use SetLogger and SetDebug instead.
+func (c *Check) SetDebugLogger(logger DebugLogger) {
+	c.debugLogger = logger
+}
+
+// SetLogger sets the logger the Check logs to.  It should not be called while
+// the Check is executing.
+func (c *Check) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+}
+
+// HasLogger returns true if the Check has its own logger rather than using
+// slog.Default().
+func (c *Check) HasLogger() bool {
+	return c.logger != nil
+}
+
+// Logger returns the Check's logger (or slog.Default() if it has none) with
+// the Check's ID and command type as attributes.
+func (c *Check) Logger() *slog.Logger {
+	return c.baseLogger().With(c.logAttrs()...)
+}
+
+func (c *Check) baseLogger() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return slog.Default()
+}
+
+func (c *Check) logAttrs() []any {
+	return []any{slog.String("check_id", c.Id), slog.String("command", CommandType(c.Command))}
+}
+
+// SetDebug enables or disables debug logging for the Check.  While enabled,
+// the Check's debug messages are logged regardless of the level its logger's
+// handler is enabled for, so a single Check can be debugged without debugging
+// every Check.  It is safe to call while the Check is executing.
+func (c *Check) SetDebug(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&c.debug, v)
+}
+
+// DebugEnabled returns true if debug logging is enabled for the Check.
+func (c *Check) DebugEnabled() bool {
+	return atomic.LoadInt32(&c.debug) != 0
+}
+
+// Debugf should be used liberally by Commands and Handlers to provide debugging information.  The message is logged
+// at slog.LevelDebug along with the Check's attributes and the caller's source location.
+func (c *Check) Debugf(format string, args ...any) {
+	if c.debugLogger != nil {
+		formatPrefix := fmt.Sprintf("[ID:%s] ", c.Id)
+
+		// get the caller's information
+		pc, _, _, ok := runtime.Caller(1)
+		if ok {
+			formatPrefix += fmt.Sprintf("[%s] ", runtime.FuncForPC(pc).Name())
+		}
+
+		c.debugLogger.Debugf(formatPrefix+format, args...)
+		return
+	}
+
+	// check first so that args are only formatted when the message is logged
+	if ctx := context.Background(); c.debugLogEnabled(ctx) {
+		c.logDebug(ctx, 3, fmt.Sprintf(format, args...))
+	}
+}
+
+// debugLogEnabled returns true if the Check's debug logging or its logger's
+// handler is enabled for slog.LevelDebug.
+func (c *Check) debugLogEnabled(ctx context.Context) bool {
+	return c.DebugEnabled() || c.baseLogger().Enabled(ctx, slog.LevelDebug)
+}
+
+// logDebug logs msg and attrs at slog.LevelDebug if the Check's debug logging
+// or its logger's handler is enabled for it.  skip is the number of callers to
+// skip to find the message's source, as with runtime.Callers.
+func (c *Check) logDebug(ctx context.Context, skip int, msg string, attrs ...slog.Attr) {
+	if !c.debugLogEnabled(ctx) {
+		return
+	}
+	logger := c.baseLogger()
+
+	var pcs [1]uintptr
+	runtime.Callers(skip, pcs[:])
+	r := slog.NewRecord(time.Now(), slog.LevelDebug, msg, pcs[0])
+	r.Add(c.logAttrs()...)
+	r.AddAttrs(attrs...)
+	// the handler is called directly so that a Check with debug enabled is logged even if the handler is not
+	_ = logger.Handler().Handle(ctx, r)
+}
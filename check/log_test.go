@@ -0,0 +1,90 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCheck_DebugLogsOnlyWhenEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	c := New("check-1", WithCommand(&stateCommand{state: StateWarn}), WithLogger(logger))
+
+	c.Debugf("not logged")
+	if buf.Len() != 0 {
+		t.Fatalf("Debugf(): expected nothing logged without debug enabled, got %s", buf.String())
+	}
+
+	c.SetDebug(true)
+	c.Debugf("value is %d", 42)
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Debugf(): expected a JSON log entry, got %q", buf.String())
+	}
+	if entry["msg"] != "value is 42" || entry["level"] != "DEBUG" || entry["check_id"] != "check-1" {
+		t.Errorf("Debugf(): unexpected log entry %v", entry)
+	}
+
+	buf.Reset()
+	_ = c.Execute()
+	if !strings.Contains(buf.String(), `"msg":"check result","check_id":"check-1","command":"*check.stateCommand","state":"WARN"`) {
+		t.Errorf("Execute(): expected structured check result, got %s", buf.String())
+	}
+
+	c.SetDebug(false)
+	buf.Reset()
+	_ = c.Execute()
+	if buf.Len() != 0 {
+		t.Errorf("Execute(): expected nothing logged after debug disabled, got %s", buf.String())
+	}
+}
+
+// formatCounter counts how many times it is formatted.
+type formatCounter struct {
+	n int
+}
+
+func (f *formatCounter) String() string {
+	f.n++
+	return "formatted"
+}
+
+func TestCheck_DebugfFormatsOnlyWhenEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	c := New("check-1", WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))))
+	arg := &formatCounter{}
+
+	c.Debugf("value is %s", arg)
+	if arg.n != 0 {
+		t.Errorf("Debugf(): expected arguments not to be formatted with debug disabled, formatted %d times", arg.n)
+	}
+
+	c.SetDebug(true)
+	c.Debugf("value is %s", arg)
+	if arg.n != 1 || !strings.Contains(buf.String(), "value is formatted") {
+		t.Errorf("Debugf(): expected arguments to be formatted once with debug enabled, formatted %d times: %s", arg.n, buf.String())
+	}
+}
+
+type legacyDebugLogger struct {
+	messages []string
+}
+
+func (l *legacyDebugLogger) Debugf(format string, args ...any) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestCheck_DebugfUsesDeprecatedDebugLogger(t *testing.T) {
+	logger := &legacyDebugLogger{}
+	c := New("check-1", WithDebugLogger(logger))
+
+	c.Debugf("hello %s", "world")
+
+	if len(logger.messages) != 1 || !strings.HasPrefix(logger.messages[0], "[ID:check-1] ") || !strings.HasSuffix(logger.messages[0], "hello world") {
+		t.Errorf("Debugf(): unexpected messages %q", logger.messages)
+	}
+}
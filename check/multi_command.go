@@ -0,0 +1,180 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-multierror"
+	"strings"
+	"sync"
+)
+
+// SubCommand is one of the Commands run by a MultiCommand.
+type SubCommand struct {
+	// Name identifies the SubCommand in the MultiCommand's reason code.
+	Name string `json:"name,omitempty"`
+
+	// MetricPrefix is prepended to the label of each metric the Command
+	// returns, so that several Commands may return the same labels.
+	MetricPrefix string `json:"metricPrefix,omitempty"`
+
+	Command Command `json:"-"`
+}
+
+// MultiCommand is a Command that runs several Commands as one, such as to poll
+// CPU, memory and interface counters of a device with a single Check.
+//
+// The metrics of every SubCommand are merged into one Result.  The Result's
+// state is the worst of the SubCommands' states as determined by
+// ResultState.Overrides, except that a SubCommand that failed (with an
+// UNKNOWN state) is never masked by an OK one.  Its reason code is that of the
+// SubCommand that produced the state, prefixed by the SubCommand's Name and a
+// colon (ex. "cpu:THRESHOLD_EXCEEDED").
+type MultiCommand struct {
+	Commands []SubCommand `json:"commands"`
+
+	// Parallel runs the Commands concurrently rather than one after another.
+	Parallel bool `json:"parallel,omitempty"`
+}
+
+// NewMultiCommand creates a MultiCommand that runs commands sequentially.
+func NewMultiCommand(commands ...SubCommand) *MultiCommand {
+	return &MultiCommand{Commands: commands}
+}
+
+func (m *MultiCommand) Run(chk *Check) (*Result, error) {
+	return m.RunContext(context.Background(), chk)
+}
+
+func (m *MultiCommand) RunContext(ctx context.Context, chk *Check) (*Result, error) {
+	if len(m.Commands) == 0 {
+		return MakeUnknownResult("CMD_FAILURE"), errors.New("multi command has no commands")
+	}
+
+	results := make([]*Result, len(m.Commands))
+	errs := make([]error, len(m.Commands))
+	if m.Parallel {
+		var wg sync.WaitGroup
+		for i := range m.Commands {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], errs[i] = m.runSubCommand(ctx, chk, m.Commands[i])
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range m.Commands {
+			results[i], errs[i] = m.runSubCommand(ctx, chk, m.Commands[i])
+		}
+	}
+
+	var err error
+	var worst int
+	var metrics []ResultMetric
+	for i, sub := range m.Commands {
+		if errs[i] != nil {
+			err = multierror.Append(err, fmt.Errorf("%s: %w", sub.Name, errs[i]))
+		}
+		if i > 0 && mergedStateOverrides(results[i].State, results[worst].State) {
+			worst = i
+		}
+		for _, metric := range results[i].Metrics {
+			metric.Label = sub.MetricPrefix + metric.Label
+			metrics = append(metrics, metric)
+		}
+	}
+
+	reasonCode := results[worst].ReasonCode
+	if name := m.Commands[worst].Name; name != "" && reasonCode != "" {
+		reasonCode = name + ":" + reasonCode
+	}
+	chk.Debugf("multi command state %s from command %q", results[worst].State, m.Commands[worst].Name)
+
+	return NewResult(results[worst].State, reasonCode, metrics), err
+}
+
+// mergedStateOverrides returns true if a SubCommand's state s overrides z in a
+// MultiCommand's Result.  Unlike ResultState.Overrides, UNKNOWN overrides OK
+// rather than the other way around.
+func mergedStateOverrides(s, z ResultState) bool {
+	if s == StateUnknown {
+		return z == StateOk
+	}
+	return s != StateOk && s.Overrides(z)
+}
+
+// runSubCommand runs sub, substituting an UNKNOWN Result if it returns none.
+func (m *MultiCommand) runSubCommand(ctx context.Context, chk *Check, sub SubCommand) (*Result, error) {
+	if sub.Command == nil {
+		return MakeUnknownResult("CMD_FAILURE"), errors.New("command not defined")
+	}
+
+	result, err := CommandWithContext(sub.Command).RunContext(ctx, subCommandCheck(chk, sub.MetricPrefix))
+	if result == nil {
+		result = MakeUnknownResult("CMD_FAILURE")
+	}
+	return result, err
+}
+
+// subCommandCheck returns a snapshot of chk whose previous Results contain
+// only the metrics labelled with prefix, with prefix removed.  This lets
+// Commands that compute deltas from previous Results find their own metrics.
+func subCommandCheck(chk *Check, prefix string) *Check {
+	if prefix == "" {
+		return chk
+	}
+
+	sub := chk.commandSnapshot()
+	if chk.LastResult != nil {
+		sub.LastResult = stripMetricPrefix(chk.LastResult, prefix)
+	}
+	if chk.History != nil {
+		sub.History = NewResultHistory(chk.History.Size, chk.History.MaxAge)
+		for _, r := range chk.History.Results() {
+			sub.History.Add(stripMetricPrefix(r, prefix))
+		}
+	}
+	return sub
+}
+
+func stripMetricPrefix(result *Result, prefix string) *Result {
+	r := *result
+	r.Metrics = nil
+	for _, metric := range result.Metrics {
+		if label, ok := strings.CutPrefix(metric.Label, prefix); ok {
+			metric.Label = label
+			r.Metrics = append(r.Metrics, metric)
+		}
+	}
+	return &r
+}
+
+// subCommandJSON encodes the SubCommand's Command by its registered type name.
+type subCommandJSON struct {
+	Name         string          `json:"name,omitempty"`
+	MetricPrefix string          `json:"metricPrefix,omitempty"`
+	Command      json.RawMessage `json:"command,omitempty"`
+}
+
+func (s SubCommand) MarshalJSON() ([]byte, error) {
+	j := subCommandJSON{Name: s.Name, MetricPrefix: s.MetricPrefix}
+	if s.Command != nil {
+		var err error
+		if j.Command, err = MarshalCommand(s.Command); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(j)
+}
+
+func (s *SubCommand) UnmarshalJSON(data []byte) (err error) {
+	var j subCommandJSON
+	if err = json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	s.Name, s.MetricPrefix = j.Name, j.MetricPrefix
+	s.Command, err = UnmarshalCommand(j.Command)
+	return err
+}
@@ -0,0 +1,149 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type resultCommand struct {
+	result *Result
+	err    error
+
+	lastResult *Result
+}
+
+func (c *resultCommand) Run(chk *Check) (*Result, error) {
+	c.lastResult = chk.LastResult
+	return c.result, c.err
+}
+
+func TestMultiCommand_MergesMetricsAndWorstState(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		cmd := NewMultiCommand(
+			SubCommand{Name: "cpu", MetricPrefix: "cpu_", Command: &resultCommand{
+				result: NewResult(StateWarn, "THRESHOLD_EXCEEDED", []ResultMetric{NewGaugeMetric("util", 85, ResultMetricUnitPercent)}),
+			}},
+			SubCommand{Name: "mem", MetricPrefix: "mem_", Command: &resultCommand{
+				result: NewResult(StateCrit, "THRESHOLD_EXCEEDED", []ResultMetric{NewGaugeMetric("util", 97, ResultMetricUnitPercent)}),
+			}},
+			SubCommand{Name: "ifaces", Command: &resultCommand{
+				result: NewResult(StateOk, "", []ResultMetric{NewCounterMetric("ifHCInOctets", 1000, ResultMetricUnitOctets)}),
+			}},
+		)
+		cmd.Parallel = parallel
+
+		result, err := cmd.Run(&Check{})
+		if err != nil {
+			t.Fatalf("Run(): unexpected error: %v", err)
+		}
+		if result.State != StateCrit || result.ReasonCode != "mem:THRESHOLD_EXCEEDED" {
+			t.Errorf("Run(parallel=%v): expected CRIT/mem:THRESHOLD_EXCEEDED, got %s/%s", parallel, result.State, result.ReasonCode)
+		}
+		var labels []string
+		for _, m := range result.Metrics {
+			labels = append(labels, m.Label)
+		}
+		if want := []string{"cpu_util", "mem_util", "ifHCInOctets"}; !reflect.DeepEqual(want, labels) {
+			t.Errorf("Run(parallel=%v): expected metrics %v, got %v", parallel, want, labels)
+		}
+	}
+}
+
+func TestMultiCommand_FailedCommandIsUnknown(t *testing.T) {
+	failure := errors.New("snmp timeout")
+	cmd := NewMultiCommand(
+		SubCommand{Name: "cpu", Command: &resultCommand{err: failure}},
+		SubCommand{Name: "ping", Command: &resultCommand{result: NewResult(StateOk, "", nil)}},
+	)
+
+	result, err := cmd.Run(&Check{})
+
+	if !errors.Is(err, failure) {
+		t.Errorf("Run(): expected the command's error, got %v", err)
+	}
+	if result.State != StateUnknown || result.ReasonCode != "cpu:CMD_FAILURE" {
+		t.Errorf("Run(): expected UNKNOWN/cpu:CMD_FAILURE, got %s/%s", result.State, result.ReasonCode)
+	}
+}
+
+func TestMultiCommand_OkCommandDoesNotMaskFailure(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		cmd := NewMultiCommand(
+			SubCommand{Name: "ping", Command: &resultCommand{result: NewResult(StateOk, "", nil)}},
+			SubCommand{Name: "snmp", Command: &resultCommand{result: MakeUnknownResult("CONNECTION_ERROR")}},
+			SubCommand{Name: "http", Command: &resultCommand{result: NewResult(StateOk, "", nil)}},
+		)
+		cmd.Parallel = parallel
+
+		result, _ := cmd.Run(&Check{})
+		if result.State != StateUnknown || result.ReasonCode != "snmp:CONNECTION_ERROR" {
+			t.Errorf("Run(parallel=%v): expected UNKNOWN/snmp:CONNECTION_ERROR, got %s/%s", parallel, result.State, result.ReasonCode)
+		}
+	}
+
+	cmd := NewMultiCommand(
+		SubCommand{Name: "snmp", Command: &resultCommand{result: MakeUnknownResult("CONNECTION_ERROR")}},
+		SubCommand{Name: "ping", Command: &resultCommand{result: NewResult(StateWarn, "LATENCY_HIGH", nil)}},
+	)
+	if result, _ := cmd.Run(&Check{}); result.State != StateWarn {
+		t.Errorf("Run(): expected WARN to override UNKNOWN, got %s", result.State)
+	}
+}
+
+func TestMultiCommand_PassesCommandsTheirOwnLastResult(t *testing.T) {
+	sub := &resultCommand{result: NewResult(StateOk, "", nil)}
+	cmd := NewMultiCommand(SubCommand{MetricPrefix: "cpu_", Command: sub})
+	chk := &Check{LastResult: NewResult(StateOk, "", []ResultMetric{
+		NewGaugeMetric("cpu_util", 50, ResultMetricUnitPercent),
+		NewGaugeMetric("mem_util", 60, ResultMetricUnitPercent),
+	})}
+
+	_, _ = cmd.Run(chk)
+
+	if len(sub.lastResult.Metrics) != 1 || sub.lastResult.Metric("util", nil) == nil {
+		t.Errorf("Run(): expected only the command's unprefixed metrics, got %v", sub.lastResult.Metrics)
+	}
+	if chk.LastResult.Metrics[0].Label != "cpu_util" {
+		t.Error("Run(): the Check's own LastResult was modified")
+	}
+}
+
+func TestMultiCommand_DebugCanBeToggledWhileRunning(t *testing.T) {
+	cmd := NewMultiCommand(SubCommand{MetricPrefix: "cpu_", Command: &resultCommand{result: NewResult(StateOk, "", nil)}})
+	chk := &Check{Command: cmd}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			chk.SetDebug(i%2 == 0)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_, _ = cmd.Run(chk)
+	}
+	<-done
+}
+
+func TestMultiCommand_JSONRoundTrip(t *testing.T) {
+	cmd := &MultiCommand{
+		Commands: []SubCommand{{Name: "a", MetricPrefix: "a_", Command: &encodedCommand{Addr: "10.0.0.1", Count: 3}}},
+		Parallel: true,
+	}
+
+	data, err := MarshalCommand(cmd)
+	if err != nil {
+		t.Fatalf("MarshalCommand(): unexpected error: %v", err)
+	}
+	decoded, err := UnmarshalCommand(data)
+	if err != nil {
+		t.Fatalf("UnmarshalCommand(): unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(cmd, decoded) {
+		got, _ := json.Marshal(decoded)
+		t.Errorf("json round trip: expected %s, got %s", data, got)
+	}
+}
@@ -0,0 +1,199 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrUnregisteredType is returned (wrapped) when encoding or decoding a
+// Command, Schedule or Handler whose type was never registered.
+var ErrUnregisteredType = errors.New("type not registered")
+
+// typedValue is the encoded form of a registered Command, Schedule or
+// Handler.  Config is the value itself encoded with encoding/json.
+type typedValue struct {
+	Type   string          `json:"type"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// registry maps type names to factories for one kind of interface value.
+type registry[T any] struct {
+	kind      string
+	mu        sync.RWMutex
+	factories map[string]func() T
+	names     map[reflect.Type]string
+}
+
+func newRegistry[T any](kind string) *registry[T] {
+	return &registry[T]{
+		kind:      kind,
+		factories: make(map[string]func() T),
+		names:     make(map[reflect.Type]string),
+	}
+}
+
+func (r *registry[T]) register(name string, factory func() T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if factory == nil {
+		panic(fmt.Sprintf("check: %s factory for %q is nil", r.kind, name))
+	}
+	if _, dup := r.factories[name]; dup {
+		panic(fmt.Sprintf("check: %s %q registered twice", r.kind, name))
+	}
+	r.factories[name] = factory
+	r.names[reflect.TypeOf(factory())] = name
+}
+
+func (r *registry[T]) nameOf(v T) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t := reflect.TypeOf(v)
+	if name, ok := r.names[t]; ok {
+		return name, nil
+	}
+	// a value whose pointer type was registered encodes the same way
+	if t.Kind() != reflect.Pointer {
+		if name, ok := r.names[reflect.PointerTo(t)]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s %s", ErrUnregisteredType, r.kind, t)
+}
+
+func (r *registry[T]) marshal(v T) ([]byte, error) {
+	if any(v) == nil {
+		return []byte("null"), nil
+	}
+	name, err := r.nameOf(v)
+	if err != nil {
+		return nil, err
+	}
+	config, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s %q: %w", r.kind, name, err)
+	}
+	return json.Marshal(typedValue{Type: name, Config: config})
+}
+
+func (r *registry[T]) unmarshal(data []byte) (T, error) {
+	var v T
+	if len(data) == 0 || string(data) == "null" {
+		return v, nil
+	}
+
+	var tv typedValue
+	if err := json.Unmarshal(data, &tv); err != nil {
+		return v, err
+	}
+
+	r.mu.RLock()
+	factory, ok := r.factories[tv.Type]
+	r.mu.RUnlock()
+	if !ok {
+		return v, fmt.Errorf("%w: %s %q", ErrUnregisteredType, r.kind, tv.Type)
+	}
+
+	v = factory()
+	if len(tv.Config) > 0 {
+		if err := json.Unmarshal(tv.Config, v); err != nil {
+			return v, fmt.Errorf("failed to decode %s %q: %w", r.kind, tv.Type, err)
+		}
+	}
+	return v, nil
+}
+
+var (
+	commands  = newRegistry[Command]("command")
+	schedules = newRegistry[Schedule]("schedule")
+	handlers  = newRegistry[Handler]("handler")
+)
+
+func init() {
+	RegisterCommand("multi", func() Command { return &MultiCommand{} })
+
+	RegisterSchedule("periodic", func() Schedule { return &PeriodicSchedule{} })
+	RegisterSchedule("cron", func() Schedule { return &CronSchedule{} })
+	RegisterSchedule("spread", func() Schedule { return &SpreadSchedule{} })
+	RegisterSchedule("backoff", func() Schedule { return &BackoffSchedule{} })
+}
+
+// RegisterCommand makes a Command type encodable by name.  factory must
+// return a new pointer to the type, which its encoded config is decoded into.
+// Exported fields are encoded with encoding/json, so anything else (such as
+// an injected getter or pinger) must be set again after decoding.  It panics
+// if name is registered twice.
+func RegisterCommand(name string, factory func() Command) {
+	commands.register(name, factory)
+}
+
+// RegisterSchedule makes a Schedule type encodable by name.  See
+// RegisterCommand.
+func RegisterSchedule(name string, factory func() Schedule) {
+	schedules.register(name, factory)
+}
+
+// RegisterHandler makes a Handler type encodable by name.  See
+// RegisterCommand.
+func RegisterHandler(name string, factory func() Handler) {
+	handlers.register(name, factory)
+}
+
+// CommandType returns the name cmd's type was registered with, or its Go type
+// if it was not registered (ex. "*mypkg.Command").  It returns "" for nil.
+func CommandType(cmd Command) string {
+	if cmd == nil {
+		return ""
+	}
+	if name, err := commands.nameOf(cmd); err == nil {
+		return name
+	}
+	return reflect.TypeOf(cmd).String()
+}
+
+// HandlerType returns the name handler's type was registered with, or its Go
+// type if it was not registered.  It returns "" for nil.
+func HandlerType(handler Handler) string {
+	if handler == nil {
+		return ""
+	}
+	if name, err := handlers.nameOf(handler); err == nil {
+		return name
+	}
+	return reflect.TypeOf(handler).String()
+}
+
+// MarshalCommand encodes cmd as its registered type name and config.
+func MarshalCommand(cmd Command) ([]byte, error) {
+	return commands.marshal(cmd)
+}
+
+// UnmarshalCommand decodes a Command encoded by MarshalCommand.
+func UnmarshalCommand(data []byte) (Command, error) {
+	return commands.unmarshal(data)
+}
+
+// MarshalSchedule encodes schedule as its registered type name and config.
+func MarshalSchedule(schedule Schedule) ([]byte, error) {
+	return schedules.marshal(schedule)
+}
+
+// UnmarshalSchedule decodes a Schedule encoded by MarshalSchedule.
+func UnmarshalSchedule(data []byte) (Schedule, error) {
+	return schedules.unmarshal(data)
+}
+
+// MarshalHandler encodes handler as its registered type name and config.
+func MarshalHandler(handler Handler) ([]byte, error) {
+	return handlers.marshal(handler)
+}
+
+// UnmarshalHandler decodes a Handler encoded by MarshalHandler.
+func UnmarshalHandler(data []byte) (Handler, error) {
+	return handlers.unmarshal(data)
+}
@@ -1,7 +1,13 @@
 package check
 
 import (
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -56,13 +62,50 @@ func NewResultStateFromString(state string) ResultState {
 	}
 }
 
+// StateType is whether a Result's state is soft or hard.  A non-OK state
+// starts out soft and only becomes hard once the Check has seen MaxAttempts
+// consecutive non-OK results.  Only hard states open Incidents.
+type StateType uint8
+
+const (
+	StateTypeHard StateType = 0
+	StateTypeSoft StateType = 1
+)
+
+func (t StateType) String() string {
+	if t == StateTypeSoft {
+		return "SOFT"
+	}
+	return "HARD"
+}
+
 // Result contains the state, reason, metrics and time of a check.Command.
 type Result struct {
-	Id         uuid.UUID
-	State      ResultState
-	ReasonCode string
-	Metrics    []ResultMetric
-	Time       time.Time
+	Id         uuid.UUID      `json:"id"`
+	State      ResultState    `json:"state"`
+	ReasonCode string         `json:"reasonCode"`
+	Metrics    []ResultMetric `json:"metrics"`
+
+	// Time is when the Result was produced.  Check.Execute() sets it from the
+	// Check's Environment.
+	Time time.Time `json:"time"`
+
+	// StateType is whether State is soft or hard.  This is set by
+	// Check.Execute().
+	StateType StateType `json:"stateType"`
+
+	// Attempt is the number of consecutive non-OK results (up to the Check's
+	// MaxAttempts) including this one, or 0 when State is OK.  This is set by
+	// Check.Execute().
+	Attempt int `json:"attempt,omitempty"`
+
+	// UnreachableVia is the ID of the parent Check that was down when this
+	// Result was produced, in which case the Command was never run.
+	UnreachableVia string `json:"unreachableVia,omitempty"`
+
+	// InDowntime is true if the Check was in scheduled downtime when this
+	// Result was produced.  Results in downtime never open Incidents.
+	InDowntime bool `json:"inDowntime,omitempty"`
 }
 
 // NewResult creates a new Result with the provided attributes and the time
@@ -77,6 +120,17 @@ func NewResult(state ResultState, reasonCode string, metrics []ResultMetric) *Re
 	}
 }
 
+// Metric returns the first metric with the given label and tags, or nil if
+// there is none.
+func (r *Result) Metric(label string, tags map[string]string) *ResultMetric {
+	for i := range r.Metrics {
+		if r.Metrics[i].Label == label && r.Metrics[i].HasTags(tags) {
+			return &r.Metrics[i]
+		}
+	}
+	return nil
+}
+
 // justifiesNewIncidentForCheck determines if the Result 'r' for the Check
 // 'check' has undergone state changes that justify the creation of a new
 // incident.
@@ -94,16 +148,42 @@ func (r Result) justifiesNewIncidentForCheck(check *Check) bool {
 		return false
 	}
 
-	// current result NOT OK and last incident exists
-	if lastIncident != nil {
+	// a Check that cannot be reached because its parent is down is the parent's incident
+	if r.UnreachableVia != "" {
+		return false
+	}
+
+	// nobody should be alerted about checks under maintenance
+	if r.InDowntime {
+		return false
+	}
+
+	// soft states are retried before they are trusted enough for an incident
+	if r.StateType == StateTypeSoft {
+		return false
+	}
+
+	// state changes while flapping are covered by the flapping incident
+	if check.IsFlapping {
+		return false
+	}
+
+	// current result NOT OK and unresolved last incident exists
+	if lastIncident != nil && !lastIncident.IsResolved() {
+		// flapping just stopped on a non-OK state, which deserves its own incident
+		if lastIncident.Type == IncidentTypeFlapping {
+			return true
+		}
 		// last incident to-state different from this result state
 		return lastIncident.ToState != r.State
 	}
 
-	// current result NOT OK and NO last incident exists and last result exists
+	// current result NOT OK and NO open incident exists and last result exists
 	if lastResult != nil {
-		// last result state different from new state
-		return lastResult.State != r.State
+		// last result state different from new state, or the same state has now turned hard, or the last result
+		// could not have opened an incident for the same state
+		return lastResult.State != r.State || lastResult.StateType == StateTypeSoft ||
+			lastResult.InDowntime || lastResult.UnreachableVia != ""
 	}
 
 	// not ok, no last incident, no last result
@@ -135,6 +215,35 @@ const (
 	ResultMetricGauge ResultMetricType = 2
 )
 
+// ResultMetricKind is the numeric type a ResultMetric's value is stored as.
+type ResultMetricKind uint8
+
+const (
+	// ResultMetricKindNone is the kind of a ResultMetric that was not created
+	// by one of the constructors, whose Value is parsed when it is read.
+	ResultMetricKindNone ResultMetricKind = iota
+	ResultMetricKindInt64
+	ResultMetricKindUint64
+	ResultMetricKindFloat64
+)
+
+// ErrInvalidMetricValue is returned (wrapped) when a ResultMetric's Value is
+// not a number.
+var ErrInvalidMetricValue = errors.New("invalid metric value")
+
+// ResultMetricUnit is the unit a ResultMetric's value is measured in.
+type ResultMetricUnit string
+
+const (
+	ResultMetricUnitNone          ResultMetricUnit = ""
+	ResultMetricUnitMilliseconds  ResultMetricUnit = "ms"
+	ResultMetricUnitBytes         ResultMetricUnit = "bytes"
+	ResultMetricUnitOctets        ResultMetricUnit = "octets"
+	ResultMetricUnitPercent       ResultMetricUnit = "percent"
+	ResultMetricUnitBitsPerSecond ResultMetricUnit = "bps"
+	ResultMetricUnitCount         ResultMetricUnit = "count"
+)
+
 // ResultMetric is a metric that lives in a Result and was produced by a Command.
 // For example, an HTTP check may have a "resp_time" Gauge metric that measured
 // how long it took to get the HTTP response from an endpoint.  Another example
@@ -142,14 +251,232 @@ const (
 // inbound bandwidth utilization of an interface.
 type ResultMetric struct {
 	// Label is an identifier for the metric (ex. avg_rtt_ms, temperature_f).
-	Label string
+	Label string `json:"label"`
+
+	// Value is the metric's numeric value exactly as the Command received or
+	// formatted it.  The constructors parse it once, so Int64(), Uint64() and
+	// Float64() return the parsed value without parsing it again.  It should
+	// not be changed after the metric is created.
+	Value string `json:"value"`
 
-	// Value is the metric's numeric value.  It is a string so that it can hold
-	// any number, and it is up to the user to convert this into whatever
-	// numerical type they need during processing.
-	Value string
+	// Kind is the numeric type Value was parsed as.  It is set by the
+	// constructors.
+	Kind ResultMetricKind `json:"kind,omitempty"`
 
 	// Type is the type of metric Value is.  Can be ResultMetricCounter or
 	// ResultMetricGauge.
-	Type ResultMetricType
+	Type ResultMetricType `json:"type,omitempty"`
+
+	// Unit is what Value is measured in, if known.
+	Unit ResultMetricUnit `json:"unit,omitempty"`
+
+	// Tags are key/value dimensions that distinguish metrics sharing a Label
+	// (ex. ifIndex=3, pool=residential).
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// the parsed Value, in the field matching Kind
+	i int64
+	u uint64
+	f float64
+}
+
+// NewGaugeMetric creates a gauge ResultMetric from a float64.
+func NewGaugeMetric(label string, value float64, unit ResultMetricUnit) ResultMetric {
+	return ResultMetric{
+		Label: label,
+		Value: strconv.FormatFloat(value, 'f', -1, 64),
+		Kind:  ResultMetricKindFloat64,
+		Type:  ResultMetricGauge,
+		Unit:  unit,
+		f:     value,
+	}
+}
+
+// NewCounterMetric creates a counter ResultMetric from a uint64.
+func NewCounterMetric(label string, value uint64, unit ResultMetricUnit) ResultMetric {
+	return ResultMetric{
+		Label: label,
+		Value: strconv.FormatUint(value, 10),
+		Kind:  ResultMetricKindUint64,
+		Type:  ResultMetricCounter,
+		Unit:  unit,
+		u:     value,
+	}
+}
+
+// ParseMetric creates a ResultMetric from a number received as a string,
+// keeping the string as its Value.  Value is stored as an int64 if it is
+// one, otherwise a uint64, otherwise a float64, except that counters are
+// stored as a uint64 if they are one.  It returns an error wrapping
+// ErrInvalidMetricValue if value is not a number.
+func ParseMetric(label, value string, metricType ResultMetricType, unit ResultMetricUnit) (ResultMetric, error) {
+	m := ResultMetric{
+		Label: label,
+		Value: value,
+		Type:  metricType,
+		Unit:  unit,
+	}
+	return m, m.parse()
+}
+
+// parse sets the parsed value from Value according to Kind, or sets Kind
+// from Value if it has none.
+func (m *ResultMetric) parse() error {
+	var err error
+	switch m.Kind {
+	case ResultMetricKindInt64:
+		m.i, err = strconv.ParseInt(m.Value, 10, 64)
+	case ResultMetricKindUint64:
+		m.u, err = strconv.ParseUint(m.Value, 10, 64)
+	case ResultMetricKindFloat64:
+		m.f, err = strconv.ParseFloat(m.Value, 64)
+	default:
+		if m.Type == ResultMetricCounter {
+			if m.u, err = strconv.ParseUint(m.Value, 10, 64); err == nil {
+				m.Kind = ResultMetricKindUint64
+				return nil
+			}
+		}
+		if m.i, err = strconv.ParseInt(m.Value, 10, 64); err == nil {
+			m.Kind = ResultMetricKindInt64
+		} else if m.u, err = strconv.ParseUint(m.Value, 10, 64); err == nil {
+			m.Kind = ResultMetricKindUint64
+		} else if m.f, err = strconv.ParseFloat(m.Value, 64); err == nil {
+			m.Kind = ResultMetricKindFloat64
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w %q for metric %s", ErrInvalidMetricValue, m.Value, m.Label)
+	}
+	return nil
+}
+
+// WithPrecision returns a copy of a float64 metric with its Value formatted
+// with prec digits after the decimal point, and its value rounded to match.
+// Other metrics are returned unchanged.
+func (m ResultMetric) WithPrecision(prec int) ResultMetric {
+	if m.Kind != ResultMetricKindFloat64 {
+		return m
+	}
+	m.Value = strconv.FormatFloat(m.f, 'f', prec, 64)
+	m.f, _ = strconv.ParseFloat(m.Value, 64)
+	return m
+}
+
+// WithTags returns a copy of the metric with tags added to its Tags.
+func (m ResultMetric) WithTags(tags map[string]string) ResultMetric {
+	merged := make(map[string]string, len(m.Tags)+len(tags))
+	for k, v := range m.Tags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	m.Tags = merged
+	return m
+}
+
+// HasTags returns true if the metric has all the given tags.
+func (m ResultMetric) HasTags(tags map[string]string) bool {
+	for k, v := range tags {
+		if tv, ok := m.Tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Int64 returns the metric's value as an int64.  A float64 value must be a
+// whole number to be returned.
+func (m ResultMetric) Int64() (int64, error) {
+	if err := m.parsed(); err != nil {
+		return 0, err
+	}
+	switch m.Kind {
+	case ResultMetricKindInt64:
+		return m.i, nil
+	case ResultMetricKindUint64:
+		if m.u <= math.MaxInt64 {
+			return int64(m.u), nil
+		}
+		return 0, m.numError("ParseInt", strconv.ErrRange)
+	default:
+		if m.f != math.Trunc(m.f) {
+			return 0, m.numError("ParseInt", strconv.ErrSyntax)
+		}
+		if m.f < math.MinInt64 || m.f >= math.MaxInt64 {
+			return 0, m.numError("ParseInt", strconv.ErrRange)
+		}
+		return int64(m.f), nil
+	}
+}
+
+// Uint64 returns the metric's value as a uint64.  A float64 value must be a
+// whole number to be returned.
+func (m ResultMetric) Uint64() (uint64, error) {
+	if err := m.parsed(); err != nil {
+		return 0, err
+	}
+	switch m.Kind {
+	case ResultMetricKindInt64:
+		if m.i >= 0 {
+			return uint64(m.i), nil
+		}
+		return 0, m.numError("ParseUint", strconv.ErrSyntax)
+	case ResultMetricKindUint64:
+		return m.u, nil
+	default:
+		if m.f != math.Trunc(m.f) || m.f < 0 {
+			return 0, m.numError("ParseUint", strconv.ErrSyntax)
+		}
+		if m.f >= math.MaxUint64 {
+			return 0, m.numError("ParseUint", strconv.ErrRange)
+		}
+		return uint64(m.f), nil
+	}
+}
+
+// Float64 returns the metric's value as a float64.  Integers too large for a
+// float64 lose precision.
+func (m ResultMetric) Float64() (float64, error) {
+	if err := m.parsed(); err != nil {
+		return 0, err
+	}
+	switch m.Kind {
+	case ResultMetricKindInt64:
+		return float64(m.i), nil
+	case ResultMetricKindUint64:
+		return float64(m.u), nil
+	default:
+		return m.f, nil
+	}
+}
+
+// parsed parses the metric's Value if it was not created by a constructor.
+// It is called on copies of the metric, so the parsed value is not kept.
+func (m *ResultMetric) parsed() error {
+	if m.Kind != ResultMetricKindNone {
+		return nil
+	}
+	return m.parse()
+}
+
+func (m ResultMetric) numError(fn string, err error) error {
+	return &strconv.NumError{Func: fn, Num: m.Value, Err: err}
+}
+
+// TagsString returns the metric's tags as a comma separated list of sorted
+// key=value pairs.
+func (m ResultMetric) TagsString() string {
+	keys := make([]string, 0, len(m.Tags))
+	for k := range m.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + m.Tags[k]
+	}
+	return strings.Join(pairs, ",")
 }
@@ -1,7 +1,10 @@
 package check
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestJustifiesNewIncidentForCheck(t *testing.T) {
@@ -67,6 +70,24 @@ func TestJustifiesNewIncidentForCheck(t *testing.T) {
 			result: Result{State: StateCrit},
 			want:   true,
 		},
+		{ // soft states never justify a new incident
+			check:  Check{},
+			result: Result{State: StateCrit, StateType: StateTypeSoft},
+			want:   false,
+		},
+		{ // last result was soft crit, new result is hard crit, new incident
+			check:  Check{LastResult: &Result{State: StateCrit, StateType: StateTypeSoft}},
+			result: Result{State: StateCrit},
+			want:   true,
+		},
+		{ // previous incident is resolved and crit, last result ok, new result is crit, new incident
+			check: Check{
+				Incident:   &Incident{ToState: StateCrit, Resolved: &time.Time{}},
+				LastResult: &Result{State: StateOk},
+			},
+			result: Result{State: StateCrit},
+			want:   true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -125,3 +146,100 @@ func TestMakeUnknownResult(t *testing.T) {
 		t.Errorf("MakeUnknownResult() returned Result with Metrics %v, expected none", r.Metrics)
 	}
 }
+
+func TestResultMetric_NumericValues(t *testing.T) {
+	m := NewCounterMetric("ifHCInOctets", 18446744073709551615, ResultMetricUnitOctets)
+	if u, err := m.Uint64(); err != nil || u != 18446744073709551615 {
+		t.Errorf("Uint64() = (%v, %v), want max uint64", u, err)
+	}
+	if _, err := m.Int64(); err == nil {
+		t.Error("Int64(): expected overflow error, got nil")
+	}
+
+	g := NewGaugeMetric("avg", 23.45, ResultMetricUnitMilliseconds)
+	if g.Value != "23.45" || g.Type != ResultMetricGauge || g.Unit != ResultMetricUnitMilliseconds {
+		t.Errorf("NewGaugeMetric() = %+v", g)
+	}
+	if f, err := g.Float64(); err != nil || f != 23.45 {
+		t.Errorf("Float64() = (%v, %v), want 23.45", f, err)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	tests := []struct {
+		value      string
+		metricType ResultMetricType
+		wantKind   ResultMetricKind
+	}{
+		{value: "-5", metricType: ResultMetricGauge, wantKind: ResultMetricKindInt64},
+		{value: "5", metricType: ResultMetricCounter, wantKind: ResultMetricKindUint64},
+		{value: "18446744073709551615", metricType: ResultMetricGauge, wantKind: ResultMetricKindUint64},
+		{value: "0.12345678", metricType: ResultMetricGauge, wantKind: ResultMetricKindFloat64},
+	}
+
+	for _, tt := range tests {
+		m, err := ParseMetric("m", tt.value, tt.metricType, ResultMetricUnitNone)
+		if err != nil || m.Kind != tt.wantKind || m.Value != tt.value {
+			t.Errorf("ParseMetric(%q) = (%+v, %v), want kind %s", tt.value, m, err, tt.wantKind)
+		}
+	}
+
+	if _, err := ParseMetric("status", "up", ResultMetricGauge, ResultMetricUnitNone); !errors.Is(err, ErrInvalidMetricValue) {
+		t.Errorf("ParseMetric(): expected ErrInvalidMetricValue, got %v", err)
+	}
+}
+
+func TestResultMetric_ConvertsBetweenKinds(t *testing.T) {
+	g := NewGaugeMetric("loss", 100, ResultMetricUnitPercent)
+	if i, err := g.Int64(); err != nil || i != 100 {
+		t.Errorf("Int64() = (%v, %v), want 100", i, err)
+	}
+	if _, err := NewGaugeMetric("avg", 1.5, ResultMetricUnitNone).Uint64(); err == nil {
+		t.Error("Uint64(): expected error for a fractional value, got nil")
+	}
+	if _, err := (ResultMetric{Value: "-1"}).Uint64(); err == nil {
+		t.Error("Uint64(): expected error for a negative value, got nil")
+	}
+	if f, err := (ResultMetric{Value: "300"}).Float64(); err != nil || f != 300 {
+		t.Errorf("Float64() = (%v, %v), want 300 parsed from Value", f, err)
+	}
+	if p := NewGaugeMetric("avg", 23.456, ResultMetricUnitNone).WithPrecision(2); p.Value != "23.46" {
+		t.Errorf("WithPrecision() = %q, want 23.46", p.Value)
+	} else if f, _ := p.Float64(); f != 23.46 {
+		t.Errorf("WithPrecision(): expected value rounded to 23.46, got %v", f)
+	}
+}
+
+func TestResultMetric_UnmarshalJSONParsesValue(t *testing.T) {
+	var m ResultMetric
+	if err := json.Unmarshal([]byte(`{"label":"octets","value":"300","type":"COUNTER"}`), &m); err != nil {
+		t.Fatalf("json.Unmarshal(): unexpected error: %v", err)
+	}
+	if u, err := m.Uint64(); m.Kind != ResultMetricKindUint64 || err != nil || u != 300 {
+		t.Errorf("json.Unmarshal(): expected a uint64 of 300, got %+v", m)
+	}
+
+	if err := json.Unmarshal([]byte(`{"label":"status","value":"up"}`), &m); !errors.Is(err, ErrInvalidMetricValue) {
+		t.Errorf("json.Unmarshal(): expected ErrInvalidMetricValue, got %v", err)
+	}
+}
+
+func TestResult_MetricMatchesLabelAndTags(t *testing.T) {
+	r := &Result{Metrics: []ResultMetric{
+		NewCounterMetric("ifHCInOctets", 1, ResultMetricUnitOctets).WithTags(map[string]string{"ifIndex": "2"}),
+		NewCounterMetric("ifHCInOctets", 2, ResultMetricUnitOctets).WithTags(map[string]string{"ifIndex": "3", "ifName": "xe-0/0/3"}),
+	}}
+
+	if m := r.Metric("ifHCInOctets", map[string]string{"ifIndex": "3"}); m == nil || m.Value != "2" {
+		t.Errorf("Metric(): expected ifIndex=3 metric, got %v", m)
+	}
+	if m := r.Metric("ifHCInOctets", nil); m == nil || m.Value != "1" {
+		t.Errorf("Metric(): expected first metric without tags filter, got %v", m)
+	}
+	if m := r.Metric("ifHCInOctets", map[string]string{"ifIndex": "4"}); m != nil {
+		t.Errorf("Metric(): expected nil, got %v", m)
+	}
+	if s := r.Metrics[1].TagsString(); s != "ifIndex=3,ifName=xe-0/0/3" {
+		t.Errorf("TagsString() = %q", s)
+	}
+}
@@ -0,0 +1,80 @@
+package check
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"time"
+)
+
+// SpreadSchedule is a Schedule decorator that keeps many Checks from becoming
+// due at the same moment.  A Check that has never executed is first due at
+// an offset within Interval after Since, and the offset is derived from a
+// hash of the Check's ID so it is the same across restarts.  After that, the
+// wrapped Schedule determines when the Check is due, optionally delayed by up
+// to Jitter.
+type SpreadSchedule struct {
+	// Schedule is the wrapped Schedule.
+	Schedule Schedule `json:"-"`
+
+	// Interval is the window first executions are spread over.  When zero
+	// and Schedule is a PeriodicSchedule, its interval is used.
+	Interval time.Duration `json:"interval,omitempty"`
+
+	// Jitter is the maximum delay added to each execution after the first.
+	// The delay is pseudo-random but derived from the Check's ID and
+	// LastCheck so that it is stable for a given run.
+	Jitter time.Duration `json:"jitter,omitempty"`
+
+	// Since is the point in time first executions are spread from.
+	// NewSpreadSchedule sets it to the current time.
+	Since time.Time `json:"since"`
+}
+
+// NewSpreadSchedule wraps schedule in a SpreadSchedule.
+func NewSpreadSchedule(schedule Schedule, interval, jitter time.Duration) *SpreadSchedule {
+	return &SpreadSchedule{
+		Schedule: schedule,
+		Interval: interval,
+		Jitter:   jitter,
+		Since:    time.Now(),
+	}
+}
+
+func (s *SpreadSchedule) DueAt(check *Check) time.Time {
+	if check.LastCheck == nil {
+		return s.Since.Add(s.offset(check))
+	}
+
+	dueAt := s.Schedule.DueAt(check)
+	if s.Jitter > 0 {
+		dueAt = dueAt.Add(time.Duration(hashCheck(check, check.LastCheck.UnixNano()) % uint64(s.Jitter)))
+	}
+	return dueAt
+}
+
+// offset returns check's deterministic offset within the spread interval.
+func (s *SpreadSchedule) offset(check *Check) time.Duration {
+	interval := s.Interval
+	if interval == 0 {
+		switch ps := s.Schedule.(type) {
+		case PeriodicSchedule:
+			interval = time.Duration(ps.IntervalSeconds) * time.Second
+		case *PeriodicSchedule:
+			interval = time.Duration(ps.IntervalSeconds) * time.Second
+		}
+	}
+	if interval <= 0 {
+		return 0
+	}
+	return time.Duration(hashCheck(check, 0) % uint64(interval))
+}
+
+// hashCheck hashes check's ID along with salt.
+func hashCheck(check *Check, salt int64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(check.Id))
+	if salt != 0 {
+		_ = binary.Write(h, binary.LittleEndian, salt)
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,44 @@
+package check
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSpreadSchedule_SpreadsFirstExecutionsAcrossInterval(t *testing.T) {
+	s := NewSpreadSchedule(&PeriodicSchedule{IntervalSeconds: 60}, 0, 0)
+
+	// bucket 1000 checks into 10 six-second windows, each should get a fair share
+	buckets := make([]int, 10)
+	for i := 0; i < 1000; i++ {
+		c := &Check{Id: fmt.Sprintf("check%d", i), Schedule: s}
+		offset := c.DueAt().Sub(s.Since)
+		if offset < 0 || offset >= 60*time.Second {
+			t.Fatalf("DueAt(): offset %v outside of interval", offset)
+		}
+		if again := c.DueAt().Sub(s.Since); again != offset {
+			t.Fatalf("DueAt(): offset not deterministic, got %v then %v", offset, again)
+		}
+		buckets[offset/(6*time.Second)]++
+	}
+	for i, n := range buckets {
+		if n < 50 || n > 150 {
+			t.Errorf("DueAt(): bucket %d has %d checks, expected ~100", i, n)
+		}
+	}
+}
+
+func TestSpreadSchedule_JittersSubsequentExecutions(t *testing.T) {
+	s := NewSpreadSchedule(&PeriodicSchedule{IntervalSeconds: 60}, 0, 5*time.Second)
+	lastCheck := time.Now()
+	c := &Check{Id: "check1", Schedule: s, LastCheck: &lastCheck}
+
+	dueAt := c.DueAt()
+	if dueAt.Before(lastCheck.Add(60*time.Second)) || !dueAt.Before(lastCheck.Add(65*time.Second)) {
+		t.Errorf("DueAt(): expected within 5s after %v, got %v", lastCheck.Add(60*time.Second), dueAt)
+	}
+	if again := c.DueAt(); !again.Equal(dueAt) {
+		t.Errorf("DueAt(): jitter not stable for the same run, got %v then %v", dueAt, again)
+	}
+}
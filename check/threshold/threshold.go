@@ -0,0 +1,179 @@
+// Package threshold provides Nagios-style threshold ranges shared by the bundled Check commands.
+//
+// A range is written as one of:
+//
+//	10       alert if the value is < 0 or > 10
+//	10:      alert if the value is < 10
+//	~:10     alert if the value is > 10
+//	10:20    alert if the value is < 10 or > 20
+//	@10:20   alert if the value is >= 10 and <= 20
+//
+// See https://nagios-plugins.org/doc/guidelines.html#THRESHOLDFORMAT
+package threshold
+
+import (
+	"fmt"
+	"github.com/seankndy/gopoller/check"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Range is a parsed threshold range.  Values outside Start and End (inclusive) alert, or inside them if Inside is
+// true.  Start and End may be infinite.
+type Range struct {
+	Start  float64
+	End    float64
+	Inside bool
+}
+
+// Parse parses a range string such as "10", "10:", "~:10", "10:20" or "@10:20".
+func Parse(s string) (*Range, error) {
+	spec := strings.TrimSpace(s)
+	r := &Range{Start: 0, End: math.Inf(1)}
+
+	if strings.HasPrefix(spec, "@") {
+		r.Inside = true
+		spec = spec[1:]
+	}
+	if spec == "" {
+		return nil, fmt.Errorf("invalid threshold range %q: empty", s)
+	}
+
+	startStr, endStr, hasColon := strings.Cut(spec, ":")
+	if !hasColon {
+		startStr, endStr = "", spec
+	}
+
+	var err error
+	switch startStr {
+	case "":
+	case "~":
+		r.Start = math.Inf(-1)
+	default:
+		if r.Start, err = strconv.ParseFloat(startStr, 64); err != nil {
+			return nil, fmt.Errorf("invalid threshold range %q: bad start: %w", s, err)
+		}
+	}
+	if endStr != "" {
+		if r.End, err = strconv.ParseFloat(endStr, 64); err != nil {
+			return nil, fmt.Errorf("invalid threshold range %q: bad end: %w", s, err)
+		}
+	}
+	if r.Start > r.End {
+		return nil, fmt.Errorf("invalid threshold range %q: start is greater than end", s)
+	}
+
+	return r, nil
+}
+
+// MustParse is like Parse but panics if s cannot be parsed.
+func MustParse(s string) *Range {
+	r, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Alerts returns true if value falls on the alerting side of the range.
+func (r *Range) Alerts(value float64) bool {
+	inside := value >= r.Start && value <= r.End
+	return inside == r.Inside
+}
+
+func (r *Range) String() string {
+	var sb strings.Builder
+	if r.Inside {
+		sb.WriteString("@")
+	}
+	if math.IsInf(r.Start, -1) {
+		sb.WriteString("~:")
+	} else if r.Start != 0 || math.IsInf(r.End, 1) {
+		sb.WriteString(strconv.FormatFloat(r.Start, 'f', -1, 64) + ":")
+	}
+	if !math.IsInf(r.End, 1) {
+		sb.WriteString(strconv.FormatFloat(r.End, 'f', -1, 64))
+	}
+	return sb.String()
+}
+
+func (r *Range) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Range) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
+
+// Threshold pairs a warning and a critical Range with the reason code to
+// report when either alerts.  Either Range may be nil.
+type Threshold struct {
+	Warn       *Range
+	Crit       *Range
+	ReasonCode string
+}
+
+// New creates a Threshold from warning and critical range strings, either of
+// which may be empty.
+func New(warn, crit, reasonCode string) (*Threshold, error) {
+	t := &Threshold{ReasonCode: reasonCode}
+
+	var err error
+	if warn != "" {
+		if t.Warn, err = Parse(warn); err != nil {
+			return nil, err
+		}
+	}
+	if crit != "" {
+		if t.Crit, err = Parse(crit); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// MustNew is like New but panics if a range cannot be parsed.
+func MustNew(warn, crit, reasonCode string) *Threshold {
+	t, err := New(warn, crit, reasonCode)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Evaluate returns the state of value according to the Threshold along with
+// its ReasonCode, or OK and an empty reason code if neither Range alerts.
+func (t *Threshold) Evaluate(value float64) (check.ResultState, string) {
+	if t.Crit != nil && t.Crit.Alerts(value) {
+		return check.StateCrit, t.ReasonCode
+	}
+	if t.Warn != nil && t.Warn.Alerts(value) {
+		return check.StateWarn, t.ReasonCode
+	}
+	return check.StateOk, ""
+}
+
+// Evaluate evaluates value against t, reporting defaultReasonCode when t
+// has no ReasonCode of its own.  A nil t is always OK.
+//
+// Commands evaluate their Threshold fields alongside any of their own
+// warn/crit thresholds, and the worst state wins (see
+// check.ResultState.Overrides).
+func Evaluate(t *Threshold, value float64, defaultReasonCode string) (check.ResultState, string) {
+	if t == nil {
+		return check.StateOk, ""
+	}
+
+	state, reasonCode := t.Evaluate(value)
+	if state != check.StateOk && reasonCode == "" {
+		reasonCode = defaultReasonCode
+	}
+	return state, reasonCode
+}
@@ -0,0 +1,81 @@
+package threshold
+
+import (
+	"github.com/seankndy/gopoller/check"
+	"testing"
+)
+
+func TestRange_Alerts(t *testing.T) {
+	tests := []struct {
+		spec   string
+		value  float64
+		alerts bool
+	}{
+		{spec: "10", value: -1, alerts: true},
+		{spec: "10", value: 0, alerts: false},
+		{spec: "10", value: 10, alerts: false},
+		{spec: "10", value: 10.1, alerts: true},
+		{spec: "10:", value: 9.9, alerts: true},
+		{spec: "10:", value: 1000, alerts: false},
+		{spec: "~:10", value: -1000, alerts: false},
+		{spec: "~:10", value: 11, alerts: true},
+		{spec: "10:20", value: 9, alerts: true},
+		{spec: "10:20", value: 15, alerts: false},
+		{spec: "10:20", value: 21, alerts: true},
+		{spec: "@10:20", value: 9, alerts: false},
+		{spec: "@10:20", value: 10, alerts: true},
+		{spec: "@10:20", value: 20, alerts: true},
+		{spec: "@10:20", value: 21, alerts: false},
+		{spec: "-5.5:5.5", value: -5, alerts: false},
+	}
+
+	for _, tt := range tests {
+		r, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): unexpected error: %v", tt.spec, err)
+		}
+		if got := r.Alerts(tt.value); got != tt.alerts {
+			t.Errorf("Parse(%q).Alerts(%v) = %v, want %v", tt.spec, tt.value, got, tt.alerts)
+		}
+	}
+}
+
+func TestParse_InvalidRanges(t *testing.T) {
+	for _, spec := range []string{"", "@", "abc", "10:abc", "20:10", "~:~"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q): expected error, got nil", spec)
+		}
+	}
+}
+
+func TestRange_StringRoundTrips(t *testing.T) {
+	for _, spec := range []string{"10", "10:", "~:10", "10:20", "@10:20", "@~:0.5"} {
+		if got := MustParse(spec).String(); got != spec {
+			t.Errorf("Parse(%q).String() = %q", spec, got)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	th := MustNew("~:80", "~:90", "")
+
+	tests := []struct {
+		threshold  *Threshold
+		value      float64
+		wantState  check.ResultState
+		wantReason string
+	}{
+		{threshold: th, value: 50, wantState: check.StateOk, wantReason: ""},
+		{threshold: th, value: 85, wantState: check.StateWarn, wantReason: "USAGE_HIGH"},
+		{threshold: th, value: 95, wantState: check.StateCrit, wantReason: "USAGE_HIGH"},
+		{threshold: MustNew("", "@0", "DOWN"), value: 0, wantState: check.StateCrit, wantReason: "DOWN"},
+		{threshold: nil, value: 1e9, wantState: check.StateOk, wantReason: ""},
+	}
+
+	for _, tt := range tests {
+		state, reason := Evaluate(tt.threshold, tt.value, "USAGE_HIGH")
+		if state != tt.wantState || reason != tt.wantReason {
+			t.Errorf("Evaluate(%v) = (%v, %q), want (%v, %q)", tt.value, state, reason, tt.wantState, tt.wantReason)
+		}
+	}
+}
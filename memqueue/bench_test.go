@@ -0,0 +1,145 @@
+package memqueue
+
+import (
+	"github.com/seankndy/gopoller/check"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bucketQueue is the previous implementation of Queue (checks bucketed by the
+// Unix second they are due), kept to benchmark against.
+type bucketQueue struct {
+	checks      map[int64][]*check.Check
+	priorities  map[int64]int64
+	minPriority int64
+	sync.Mutex
+}
+
+func newBucketQueue() *bucketQueue {
+	return &bucketQueue{
+		checks:      make(map[int64][]*check.Check),
+		priorities:  make(map[int64]int64),
+		minPriority: math.MaxInt64,
+	}
+}
+
+func (m *bucketQueue) Enqueue(chk *check.Check) {
+	priority := chk.DueAt().Unix()
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.checks[priority] = append(m.checks[priority], chk)
+	if _, ok := m.priorities[priority]; !ok {
+		m.priorities[priority] = priority
+		m.minPriority = min(priority, m.minPriority)
+	}
+}
+
+func (m *bucketQueue) Dequeue() *check.Check {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.checks[m.minPriority]; !ok {
+		return nil
+	}
+	chk := m.checks[m.minPriority][0]
+	if !chk.IsDue() {
+		return nil
+	}
+
+	m.checks[m.minPriority] = m.checks[m.minPriority][1:]
+	if len(m.checks[m.minPriority]) == 0 {
+		delete(m.priorities, m.minPriority)
+		delete(m.checks, m.minPriority)
+
+		m.minPriority = math.MaxInt64
+		for p := range m.priorities {
+			m.minPriority = min(p, m.minPriority)
+		}
+	}
+
+	return chk
+}
+
+// benchmarkChecks returns n checks that all came due within the last n/10
+// seconds (a spread of 10 checks per second).
+func benchmarkChecks(n int) []*check.Check {
+	now := time.Now()
+	schedule := &check.PeriodicSchedule{IntervalSeconds: 60}
+	chks := make([]*check.Check, n)
+	for i := range chks {
+		lastCheck := now.Add(-60*time.Second - time.Duration(i)*100*time.Millisecond)
+		chks[i] = &check.Check{Id: strconv.Itoa(i), Schedule: schedule, LastCheck: &lastCheck}
+	}
+	return chks
+}
+
+type benchmarkQueue interface {
+	Enqueue(chk *check.Check)
+	Dequeue() *check.Check
+}
+
+// benchmarkEnqueueDequeue fills a queue with n due checks and then dequeues
+// them all.
+func benchmarkEnqueueDequeue(b *testing.B, n int, newQueue func() benchmarkQueue) {
+	chks := benchmarkChecks(n)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		q := newQueue()
+		for _, chk := range chks {
+			q.Enqueue(chk)
+		}
+		for j := 0; j < n; j++ {
+			if q.Dequeue() == nil {
+				b.Fatalf("Dequeue(): expected a Check, got nil after %d", j)
+			}
+		}
+	}
+}
+
+func BenchmarkQueue_EnqueueDequeue100k(b *testing.B) {
+	benchmarkEnqueueDequeue(b, 100_000, func() benchmarkQueue { return NewQueue() })
+}
+
+func BenchmarkBucketQueue_EnqueueDequeue100k(b *testing.B) {
+	benchmarkEnqueueDequeue(b, 100_000, func() benchmarkQueue { return newBucketQueue() })
+}
+
+// BenchmarkQueue_Update100k reschedules one check at a time in a queue of
+// 100k checks, as a config sync would.
+func BenchmarkQueue_Update100k(b *testing.B) {
+	chks := benchmarkChecks(100_000)
+	q := NewQueue()
+	for _, chk := range chks {
+		q.Enqueue(chk)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		q.Update(chks[i%len(chks)])
+	}
+}
+
+// BenchmarkQueue_RemoveEnqueue100k removes and re-adds one check at a time
+// in a queue of 100k checks.
+func BenchmarkQueue_RemoveEnqueue100k(b *testing.B) {
+	chks := benchmarkChecks(100_000)
+	q := NewQueue()
+	for _, chk := range chks {
+		q.Enqueue(chk)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		chk := q.Remove(chks[i%len(chks)].Id)
+		q.Enqueue(chk)
+	}
+}
@@ -1,97 +1,152 @@
 package memqueue
 
 import (
+	"container/heap"
+	"context"
 	"github.com/seankndy/gopoller/check"
-	"math"
 	"sync"
+	"time"
 )
 
-// Queue is a min priority queue that stores its checks in memory (map).
-// Priorities are derived from the Check's DueAt() timestamp so that the
-// checks with the oldest timestamps come out first.
+// Queue is a min priority queue that stores its checks in memory (binary
+// heap).  Priorities are derived from the Check's DueAt() timestamp, to the
+// nanosecond, so that the checks with the oldest timestamps come out first.
+// Checks due at the same instant come out in the order they were enqueued.
+//
+// Checks are also indexed by ID, so only one Check with a given ID is ever in
+// the queue and it can be looked up, rescheduled or removed in O(log n).
 type Queue struct {
-	checks      map[int64][]*check.Check
-	total       uint64
-	priorities  map[int64]int64
-	minPriority int64
+	checks checkHeap
+	ids    map[string]*item
+	// seq is the sequence number given to the next enqueued check
+	seq uint64
+	// enqueued is closed by Enqueue and Update to wake DequeueWait callers.
+	// It is nil while no caller is waiting.
+	enqueued chan struct{}
 	sync.RWMutex
 }
 
+// item is a check in the heap.
+type item struct {
+	chk   *check.Check
+	dueAt int64  // chk.DueAt() in Unix nanoseconds when it was (re)scheduled
+	seq   uint64 // orders checks with equal dueAt
+	index int    // position in the heap
+}
+
 func NewQueue() *Queue {
 	return &Queue{
-		checks:      make(map[int64][]*check.Check),
-		priorities:  make(map[int64]int64),
-		minPriority: math.MaxInt64,
+		ids: make(map[string]*item),
 	}
 }
 
+// Enqueue adds chk to the queue.  If a check with the same ID is already in
+// the queue, it is replaced by chk.
 func (m *Queue) Enqueue(chk *check.Check) {
 	chk.Executed = false
-	priority := chk.DueAt().Unix()
+	dueAt := chk.DueAt().UnixNano()
 
 	m.Lock()
 	defer m.Unlock()
 
-	m.checks[priority] = append(m.checks[priority], chk)
-	m.total++
-
-	_, ok := m.priorities[priority]
-	if !ok {
-		m.priorities[priority] = priority
-		m.minPriority = int64(math.Min(float64(priority), float64(m.minPriority)))
+	if it, ok := m.ids[chk.Id]; ok {
+		m.reschedule(it, chk, dueAt)
+	} else {
+		it = &item{chk: chk, dueAt: dueAt, seq: m.seq}
+		m.seq++
+		heap.Push(&m.checks, it)
+		m.ids[chk.Id] = it
 	}
+
+	m.wake()
 }
 
 func (m *Queue) Dequeue() *check.Check {
 	m.Lock()
 	defer m.Unlock()
 
-	_, ok := m.checks[m.minPriority]
-	if !ok {
-		return nil
-	}
+	return m.dequeue()
+}
 
-	// if top-most Check is not due, then nothing is due.
-	chk := m.checks[m.minPriority][0]
-	if !chk.IsDue() {
-		return nil
-	}
+// DequeueWait blocks until a check is due and returns it, or returns nil once ctx is done.
+func (m *Queue) DequeueWait(ctx context.Context) *check.Check {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
-	// check is due, delete it from the queue
-	m.checks[m.minPriority] = m.checks[m.minPriority][1:]
-	m.total--
+	for {
+		m.Lock()
+		chk := m.dequeue()
+		next, ok := m.nextDueAt()
+		if m.enqueued == nil {
+			m.enqueued = make(chan struct{})
+		}
+		enqueued := m.enqueued
+		m.Unlock()
 
-	// if there are no checks left at this priority, remove the priority
-	// and set minPriority to the next in line
-	if len(m.checks[m.minPriority]) == 0 {
-		delete(m.priorities, m.minPriority)
-		delete(m.checks, m.minPriority)
+		if chk != nil {
+			return chk
+		}
 
-		m.minPriority = math.MaxInt64
+		var due <-chan time.Time
+		if ok {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(time.Until(next))
+			due = timer.C
+		}
 
-		for p := range m.priorities {
-			m.minPriority = min(p, m.minPriority)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-enqueued:
+		case <-due:
 		}
 	}
+}
+
+// NextDueAt returns when the check at the front of the queue is due, or false if the queue is empty.
+func (m *Queue) NextDueAt() (time.Time, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.nextDueAt()
+}
+
+func (m *Queue) nextDueAt() (time.Time, bool) {
+	if len(m.checks) == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, m.checks[0].dueAt), true
+}
+
+func (m *Queue) dequeue() *check.Check {
+	// if top-most Check is not due, then nothing is due.
+	if len(m.checks) == 0 || m.checks[0].dueAt > time.Now().UnixNano() {
+		return nil
+	}
 
-	return chk
+	it := heap.Pop(&m.checks).(*item)
+	delete(m.ids, it.chk.Id)
+	return it.chk
 }
 
 func (m *Queue) Flush() {
 	m.Lock()
 	defer m.Unlock()
 
-	m.checks = make(map[int64][]*check.Check)
-	m.priorities = make(map[int64]int64)
-	m.minPriority = math.MaxInt64
-	m.total = 0
+	m.checks = nil
+	m.ids = make(map[string]*item)
 }
 
 func (m *Queue) Count() uint64 {
 	m.RLock()
 	defer m.RUnlock()
 
-	return m.total
+	return uint64(len(m.checks))
 }
 
 // All returns every check in the queue.
@@ -99,12 +154,120 @@ func (m *Queue) All() []*check.Check {
 	m.RLock()
 	defer m.RUnlock()
 
-	all := make([]*check.Check, 0, m.total)
-	for i := range m.checks {
-		for j := range m.checks[i] {
-			all = append(all, m.checks[i][j])
-		}
+	all := make([]*check.Check, len(m.checks))
+	for i, it := range m.checks {
+		all[i] = it.chk
 	}
 
 	return all
 }
+
+// Remove removes the check with the given ID from the queue and returns it, or
+// returns nil if it is not in the queue.
+func (m *Queue) Remove(id string) *check.Check {
+	m.Lock()
+	defer m.Unlock()
+
+	it, ok := m.ids[id]
+	if !ok {
+		return nil
+	}
+
+	heap.Remove(&m.checks, it.index)
+	delete(m.ids, id)
+	return it.chk
+}
+
+// Get returns the check with the given ID without removing it from the queue,
+// or returns nil if it is not in the queue.  The check must not be modified
+// while it is in the queue; use Update to change when it is due.
+func (m *Queue) Get(id string) *check.Check {
+	m.RLock()
+	defer m.RUnlock()
+
+	if it, ok := m.ids[id]; ok {
+		return it.chk
+	}
+	return nil
+}
+
+// Contains returns true if a check with the given ID is in the queue.
+func (m *Queue) Contains(id string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, ok := m.ids[id]
+	return ok
+}
+
+// Update replaces the queued check with chk's ID by chk and moves it to its
+// place in the queue according to chk.DueAt().  It returns false (and does not
+// enqueue chk) if no check with chk's ID is in the queue.
+func (m *Queue) Update(chk *check.Check) bool {
+	chk.Executed = false
+	dueAt := chk.DueAt().UnixNano()
+
+	m.Lock()
+	defer m.Unlock()
+
+	it, ok := m.ids[chk.Id]
+	if !ok {
+		return false
+	}
+	m.reschedule(it, chk, dueAt)
+
+	m.wake()
+	return true
+}
+
+// wake wakes any DequeueWait callers.
+func (m *Queue) wake() {
+	if m.enqueued != nil {
+		close(m.enqueued)
+		m.enqueued = nil
+	}
+}
+
+// reschedule replaces the item's check by chk, due at dueAt, and fixes the item's position in the heap.
+func (m *Queue) reschedule(it *item, chk *check.Check, dueAt int64) {
+	it.chk = chk
+	it.dueAt = dueAt
+	it.seq = m.seq
+	m.seq++
+	heap.Fix(&m.checks, it.index)
+}
+
+// checkHeap implements heap.Interface, ordering items by dueAt then seq.
+type checkHeap []*item
+
+func (h checkHeap) Len() int {
+	return len(h)
+}
+
+func (h checkHeap) Less(i, j int) bool {
+	if h[i].dueAt != h[j].dueAt {
+		return h[i].dueAt < h[j].dueAt
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h checkHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *checkHeap) Push(x any) {
+	it := x.(*item)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *checkHeap) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return it
+}
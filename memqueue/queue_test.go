@@ -1,6 +1,7 @@
 package memqueue
 
 import (
+	"context"
 	"github.com/seankndy/gopoller/check"
 	"testing"
 	"time"
@@ -45,3 +46,137 @@ func TestMemoryCheckQueueEnqueuesAndDequeues(t *testing.T) {
 		t.Errorf("Count(): expected queue to be 1, got %v", cnt)
 	}
 }
+
+func TestMemoryCheckQueueRemoves(t *testing.T) {
+	q := NewQueue()
+
+	ninetySecAgo := time.Now().Add(-(90 * time.Second))
+	sixtySecAgo := time.Now().Add(-(60 * time.Second))
+	check1 := &check.Check{Id: "12345", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &ninetySecAgo}
+	check2 := &check.Check{Id: "54321", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &sixtySecAgo}
+	q.Enqueue(check1)
+	q.Enqueue(check2)
+
+	if c := q.Remove(check1.Id); c != check1 {
+		t.Errorf("Remove(): expected check with ID %v, got %v", check1.Id, c)
+	}
+	if c := q.Remove(check1.Id); c != nil {
+		t.Errorf("Remove(): expected nil for a removed check, got %v", c)
+	}
+	if cnt := q.Count(); cnt != 1 {
+		t.Errorf("Count(): expected queue to be 1, got %v", cnt)
+	}
+	if c := q.Dequeue(); c != check2 {
+		t.Errorf("Dequeue(): expected check with ID %v, got %v", check2.Id, c)
+	}
+}
+
+func TestMemoryCheckQueueDequeueWaitWakesOnEnqueue(t *testing.T) {
+	q := NewQueue()
+
+	inAMinute := time.Now()
+	later := &check.Check{Id: "later", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &inAMinute}
+	q.Enqueue(later)
+	if dueAt, ok := q.NextDueAt(); !ok || !dueAt.Equal(later.DueAt()) {
+		t.Errorf("NextDueAt(): expected %v, got %v (%v)", later.DueAt(), dueAt, ok)
+	}
+
+	dequeued := make(chan *check.Check)
+	go func() {
+		dequeued <- q.DequeueWait(context.Background())
+	}()
+
+	soon := time.Now().Add(-(60 * time.Second) + 100*time.Millisecond)
+	sooner := &check.Check{Id: "sooner", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &soon}
+	time.Sleep(20 * time.Millisecond)
+	q.Enqueue(sooner)
+
+	select {
+	case c := <-dequeued:
+		if c != sooner {
+			t.Errorf("DequeueWait(): expected check with ID %v, got %v", sooner.Id, c)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("DequeueWait(): expected the sooner check to be dequeued once due")
+	}
+}
+
+func TestMemoryCheckQueueDequeueWaitReturnsWhenCancelled(t *testing.T) {
+	q := NewQueue()
+	if _, ok := q.NextDueAt(); ok {
+		t.Error("NextDueAt(): expected false for an empty queue")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if c := q.DequeueWait(ctx); c != nil {
+		t.Errorf("DequeueWait(): expected nil, got %v", c)
+	}
+}
+
+func TestMemoryCheckQueueOrdersWithinASecond(t *testing.T) {
+	q := NewQueue()
+
+	base := time.Now().Add(-(90 * time.Second)).Truncate(time.Second)
+	later, earlier := base.Add(900*time.Millisecond), base.Add(100*time.Millisecond)
+	check1 := &check.Check{Id: "12345", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &later}
+	check2 := &check.Check{Id: "54321", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &earlier}
+	q.Enqueue(check1)
+	q.Enqueue(check2)
+
+	if c := q.Dequeue(); c != check2 {
+		t.Errorf("Dequeue(): expected check with ID %v, got %v", check2.Id, c)
+	}
+	if c := q.Dequeue(); c != check1 {
+		t.Errorf("Dequeue(): expected check with ID %v, got %v", check1.Id, c)
+	}
+}
+
+func TestMemoryCheckQueueLooksUpAndUpdatesById(t *testing.T) {
+	q := NewQueue()
+
+	ninetySecAgo := time.Now().Add(-(90 * time.Second))
+	thirtySecAgo := time.Now().Add(-(30 * time.Second))
+	check1 := &check.Check{Id: "12345", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &ninetySecAgo}
+	check2 := &check.Check{Id: "54321", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &thirtySecAgo} // not due yet
+	q.Enqueue(check1)
+	q.Enqueue(check2)
+
+	if !q.Contains(check2.Id) || q.Contains("missing") {
+		t.Errorf("Contains(): expected only queued IDs to be contained")
+	}
+	if c := q.Get(check2.Id); c != check2 {
+		t.Errorf("Get(): expected check with ID %v, got %v", check2.Id, c)
+	}
+	if c := q.Get("missing"); c != nil {
+		t.Errorf("Get(): expected nil for a missing check, got %v", c)
+	}
+
+	// reschedule check2 ahead of check1
+	hundredSecAgo := time.Now().Add(-(100 * time.Second))
+	updated := &check.Check{Id: check2.Id, Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &hundredSecAgo}
+	if !q.Update(updated) {
+		t.Fatalf("Update(): expected check with ID %v to be updated", updated.Id)
+	}
+	if q.Update(&check.Check{Id: "missing", Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}}) {
+		t.Errorf("Update(): expected a missing check not to be updated")
+	}
+	if cnt := q.Count(); cnt != 2 {
+		t.Errorf("Count(): expected queue to be 2, got %v", cnt)
+	}
+	if c := q.Dequeue(); c != updated {
+		t.Errorf("Dequeue(): expected updated check with ID %v, got %v", updated.Id, c)
+	}
+	if q.Contains(updated.Id) {
+		t.Errorf("Contains(): expected dequeued check not to be contained")
+	}
+
+	// enqueueing a check with a queued ID replaces it
+	q.Enqueue(&check.Check{Id: check1.Id, Schedule: &check.PeriodicSchedule{IntervalSeconds: 60}, LastCheck: &thirtySecAgo})
+	if cnt := q.Count(); cnt != 1 {
+		t.Errorf("Count(): expected queue to be 1, got %v", cnt)
+	}
+	if c := q.Dequeue(); c != nil {
+		t.Errorf("Dequeue(): expected replaced check not to be due, got %v", c)
+	}
+}
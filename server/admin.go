@@ -0,0 +1,462 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/seankndy/gopoller/check"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCheckNotFound is returned when no check has the given ID.
+	ErrCheckNotFound = errors.New("check not found")
+
+	// ErrCheckRunning is returned when a check cannot be changed because it is executing or was taken from the queue
+	// to execute.
+	ErrCheckRunning = errors.New("check is running")
+
+	// ErrCheckNotPaused is returned when resuming a check that is not paused.
+	ErrCheckNotPaused = errors.New("check is not paused")
+
+	// ErrNoIncident is returned when acknowledging a check without an unresolved incident.
+	ErrNoIncident = errors.New("check has no unresolved incident")
+
+	// ErrNotRunning is returned when triggering a check while the server is not running.
+	ErrNotRunning = errors.New("server is not running")
+
+	// ErrQueueUnsupported is returned when the server's check.Queue cannot remove checks by ID (see remover).
+	ErrQueueUnsupported = errors.New("check queue does not support this operation")
+)
+
+// lister is a check.Queue that can list the checks in it, such as a memqueue.Queue.
+type lister interface {
+	All() []*check.Check
+}
+
+// remover is a check.Queue that can remove a check from it by ID, such as a memqueue.Queue.  Changing a queued check
+// requires taking it out of the queue so that it cannot execute in the meantime.
+type remover interface {
+	Remove(id string) *check.Check
+}
+
+// runningCheck is a check that is executing.
+type runningCheck struct {
+	command string
+	started time.Time
+}
+
+// RunningCheck describes a check that is executing.
+type RunningCheck struct {
+	Id             string    `json:"id"`
+	Command        string    `json:"command,omitempty"`
+	Started        time.Time `json:"started"`
+	ElapsedSeconds float64   `json:"elapsedSeconds"`
+}
+
+// CheckStatus is a snapshot of a check's state.
+type CheckStatus struct {
+	Id         string          `json:"id"`
+	Command    string          `json:"command,omitempty"`
+	Running    bool            `json:"running"`
+	Paused     bool            `json:"paused"`
+	DueAt      *time.Time      `json:"dueAt,omitempty"`
+	LastCheck  *time.Time      `json:"lastCheck,omitempty"`
+	LastResult *check.Result   `json:"lastResult,omitempty"`
+	Incident   *check.Incident `json:"incident,omitempty"`
+}
+
+// newCheckStatus takes a snapshot of chk, which must not be executing.
+func newCheckStatus(chk *check.Check) CheckStatus {
+	status := CheckStatus{
+		Id:         chk.Id,
+		Command:    check.CommandType(chk.Command),
+		LastCheck:  chk.LastCheck,
+		LastResult: chk.LastResult,
+	}
+	if chk.Schedule != nil {
+		dueAt := chk.DueAt()
+		status.DueAt = &dueAt
+	}
+	if chk.Incident != nil {
+		// the incident keeps changing as the check executes, so copy it along with everything it appends to
+		incident := *chk.Incident
+		incident.Timeline = slices.Clone(incident.Timeline)
+		incident.StateChanges = slices.Clone(incident.StateChanges)
+		if incident.Acknowledgement != nil {
+			ack := *incident.Acknowledgement
+			incident.Acknowledgement = &ack
+		}
+		status.Incident = &incident
+	}
+	return status
+}
+
+// Checks lists the running, queued and paused checks.
+type Checks struct {
+	Running []RunningCheck `json:"running"`
+	Queued  []CheckStatus  `json:"queued"`
+	Paused  []CheckStatus  `json:"paused"`
+}
+
+// Checks returns the server's running and paused checks along with the checks in its queue, if the queue can list
+// them (see lister).  Each check's status is as of the last time it executed.
+func (s *Server) Checks() Checks {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checks := Checks{
+		Running: make([]RunningCheck, 0, len(s.running)),
+		Queued:  []CheckStatus{},
+		Paused:  make([]CheckStatus, 0, len(s.paused)),
+	}
+	for id, r := range s.running {
+		checks.Running = append(checks.Running, RunningCheck{
+			Id:             id,
+			Command:        r.command,
+			Started:        r.started,
+			ElapsedSeconds: time.Since(r.started).Seconds(),
+		})
+	}
+	for id := range s.paused {
+		checks.Paused = append(checks.Paused, s.status(id))
+	}
+	if l, ok := s.checkQueue.(lister); ok {
+		for _, chk := range l.All() {
+			status, ok := s.statuses[chk.Id]
+			if !ok {
+				status = newCheckStatus(chk)
+			}
+			checks.Queued = append(checks.Queued, status)
+		}
+	}
+
+	sort.Slice(checks.Running, func(i, j int) bool { return checks.Running[i].Id < checks.Running[j].Id })
+	sort.Slice(checks.Queued, func(i, j int) bool { return checks.Queued[i].Id < checks.Queued[j].Id })
+	sort.Slice(checks.Paused, func(i, j int) bool { return checks.Paused[i].Id < checks.Paused[j].Id })
+	return checks
+}
+
+// Check returns the status of the check with the given ID.
+func (s *Server) Check(id string) (CheckStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.statuses[id]; ok || s.busy(id) || s.paused[id] != nil {
+		return s.status(id), nil
+	}
+	if l, ok := s.checkQueue.(lister); ok {
+		for _, chk := range l.All() {
+			if chk.Id == id {
+				return newCheckStatus(chk), nil
+			}
+		}
+	}
+	return CheckStatus{Id: id}, ErrCheckNotFound
+}
+
+// status returns the last known status of the check with the given ID.  s.mu must be held.
+func (s *Server) status(id string) CheckStatus {
+	status, ok := s.statuses[id]
+	if !ok {
+		if held := s.paused[id]; held != nil {
+			status = newCheckStatus(held)
+		} else if r := s.running[id]; r != nil {
+			status = CheckStatus{Id: id, Command: r.command}
+		} else {
+			status = CheckStatus{Id: id}
+		}
+	}
+	_, status.Running = s.running[id]
+	_, status.Paused = s.paused[id]
+	return status
+}
+
+// Pause stops the check with the given ID from executing until it is resumed.  A running check finishes first.
+func (s *Server) Pause(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, paused := s.paused[id]; paused {
+		return nil
+	}
+
+	var held *check.Check
+	if r, ok := s.checkQueue.(remover); ok {
+		held = r.Remove(id)
+	}
+	// a check that is executing or about to is held once it finishes or starts (see Server.start)
+	if held == nil && !s.busy(id) {
+		if _, ok := s.statuses[id]; !ok && !s.queueHas(id) {
+			return ErrCheckNotFound
+		}
+	}
+	s.paused[id] = held
+	return nil
+}
+
+// queueHas returns true if the queue has the check with the given ID, or if the queue cannot list its checks.
+func (s *Server) queueHas(id string) bool {
+	l, ok := s.checkQueue.(lister)
+	if !ok {
+		return true
+	}
+	for _, chk := range l.All() {
+		if chk.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Resume returns a paused check to the queue.
+func (s *Server) Resume(id string) error {
+	s.mu.Lock()
+	held, paused := s.paused[id]
+	delete(s.paused, id)
+	s.mu.Unlock()
+
+	if !paused {
+		return ErrCheckNotPaused
+	}
+	if held != nil {
+		s.checkQueue.Enqueue(held)
+	}
+	return nil
+}
+
+// Trigger executes the check with the given ID now rather than when it is next due.  A paused check executes once
+// and stays paused.
+func (s *Server) Trigger(ctx context.Context, id string) error {
+	s.mu.Lock()
+	done := s.done
+	if done == nil {
+		s.mu.Unlock()
+		return ErrNotRunning
+	}
+	if s.busy(id) {
+		s.mu.Unlock()
+		return ErrCheckRunning
+	}
+
+	var chk *check.Check
+	if held := s.paused[id]; held != nil {
+		chk = held
+		s.paused[id] = nil
+	} else if r, ok := s.checkQueue.(remover); ok {
+		chk = r.Remove(id)
+	} else {
+		s.mu.Unlock()
+		return ErrQueueUnsupported
+	}
+	s.mu.Unlock()
+
+	if chk == nil {
+		return ErrCheckNotFound
+	}
+
+	select {
+	case s.triggered <- chk:
+		return nil
+	case <-done:
+		s.putBack(chk)
+		return ErrNotRunning
+	case <-ctx.Done():
+		s.putBack(chk)
+		return ctx.Err()
+	}
+}
+
+// putBack returns a check taken out by Trigger to where it was.
+func (s *Server) putBack(chk *check.Check) {
+	s.mu.Lock()
+	if _, paused := s.paused[chk.Id]; paused {
+		s.paused[chk.Id] = chk
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	s.checkQueue.Enqueue(chk)
+}
+
+// Acknowledge acknowledges the unresolved incident of the check with the given ID.
+func (s *Server) Acknowledge(id, author, comment string, options ...check.AckOption) error {
+	return s.modify(id, func(chk *check.Check) error {
+		if chk.Incident == nil || chk.Incident.IsResolved() {
+			return ErrNoIncident
+		}
+		chk.Incident.AcknowledgeAt(s.now(chk), author, comment, options...)
+		s.events.publish(newEvent(EventIncidentAcknowledged, chk).withIncident(chk.Incident))
+		return nil
+	})
+}
+
+// modify calls fn with the check with the given ID while it is guaranteed not to execute.
+func (s *Server) modify(id string, fn func(chk *check.Check) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.busy(id) {
+		return ErrCheckRunning
+	}
+
+	if held := s.paused[id]; held != nil {
+		err := fn(held)
+		s.statuses[id] = newCheckStatus(held)
+		return err
+	}
+
+	r, ok := s.checkQueue.(remover)
+	if !ok {
+		return ErrQueueUnsupported
+	}
+	chk := r.Remove(id)
+	if chk == nil {
+		return ErrCheckNotFound
+	}
+	err := fn(chk)
+	s.statuses[id] = newCheckStatus(chk)
+	s.checkQueue.Enqueue(chk)
+	return err
+}
+
+// AdminHandler returns an http.Handler serving a JSON API to inspect and control the server:
+//
+//	GET  /checks             lists the running, queued and paused checks (see Checks)
+//	GET  /checks/{id}        returns a check's status
+//	POST /checks/{id}/run    executes a check now
+//	POST /checks/{id}/pause  pauses a check
+//	POST /checks/{id}/resume resumes a paused check
+//	POST /checks/{id}/ack    acknowledges a check's incident; the optional body is a JSON object with "author",
+//	                         "comment", "expires" (RFC 3339) and "sticky"
+//
+// Errors are returned as a JSON object with an "error" message.
+func (s *Server) AdminHandler() http.Handler {
+	return http.HandlerFunc(s.serveAdminHTTP)
+}
+
+// ackRequest is the body of an acknowledgement request.
+type ackRequest struct {
+	Author  string     `json:"author"`
+	Comment string     `json:"comment"`
+	Expires *time.Time `json:"expires"`
+	Sticky  bool       `json:"sticky"`
+}
+
+func (s *Server) serveAdminHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "checks" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Checks())
+		return
+	}
+
+	id, found := strings.CutPrefix(path, "checks/")
+	if !found || id == "" {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		status, err := s.Check(id)
+		if err != nil {
+			writeError(w, errorStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+
+	i := strings.LastIndex(id, "/")
+	if r.Method != http.MethodPost || i < 0 {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var err error
+	switch id, action := id[:i], id[i+1:]; action {
+	case "run":
+		err = s.Trigger(r.Context(), id)
+	case "pause":
+		err = s.Pause(id)
+	case "resume":
+		err = s.Resume(id)
+	case "ack":
+		var req ackRequest
+		if r.ContentLength != 0 {
+			if errD := json.NewDecoder(r.Body).Decode(&req); errD != nil {
+				writeError(w, http.StatusBadRequest, errD)
+				return
+			}
+		}
+		var options []check.AckOption
+		if req.Expires != nil {
+			options = append(options, check.WithAckExpiry(*req.Expires))
+		}
+		if req.Sticky {
+			options = append(options, check.WithStickyAck())
+		}
+		err = s.Acknowledge(id, req.Author, req.Comment, options...)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrCheckNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrCheckRunning), errors.Is(err, ErrCheckNotPaused), errors.Is(err, ErrNoIncident):
+		return http.StatusConflict
+	case errors.Is(err, ErrNotRunning):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrQueueUnsupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// serveAdmin serves the admin API and internal metrics on s.AdminAddr until ctx is done.
+func (s *Server) serveAdmin(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.MetricsHandler())
+	mux.Handle("/", s.AdminHandler())
+	srv := &http.Server{Addr: s.AdminAddr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger().Error("admin api stopped", slog.String("addr", s.AdminAddr), slog.Any("error", err))
+	}
+}
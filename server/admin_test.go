@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/memqueue"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stateCommand struct {
+	state check.ResultState
+}
+
+func (c *stateCommand) Run(*check.Check) (*check.Result, error) {
+	return check.NewResult(c.state, "", nil), nil
+}
+
+func adminRequest(t *testing.T, s *Server, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.AdminHandler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestAdminHandler_ListsQueuedAndPausedChecks(t *testing.T) {
+	q := memqueue.NewQueue()
+	q.Enqueue(check.New("check-1", check.WithPeriodicSchedule(60)))
+	q.Enqueue(check.New("check-2", check.WithPeriodicSchedule(60)))
+	s := New(q)
+
+	if rec := adminRequest(t, s, http.MethodPost, "/checks/check-2/pause", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("pause: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := adminRequest(t, s, http.MethodGet, "/checks", "")
+	var checks Checks
+	if err := json.Unmarshal(rec.Body.Bytes(), &checks); err != nil {
+		t.Fatalf("list: unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	if len(checks.Queued) != 1 || checks.Queued[0].Id != "check-1" {
+		t.Errorf("list: expected check-1 queued, got %+v", checks.Queued)
+	}
+	if len(checks.Paused) != 1 || checks.Paused[0].Id != "check-2" || !checks.Paused[0].Paused {
+		t.Errorf("list: expected check-2 paused, got %+v", checks.Paused)
+	}
+
+	if rec := adminRequest(t, s, http.MethodPost, "/checks/check-2/resume", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("resume: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if q.Count() != 2 {
+		t.Errorf("resume: expected check-2 back in the queue")
+	}
+	if rec := adminRequest(t, s, http.MethodPost, "/checks/check-2/resume", ""); rec.Code != http.StatusConflict {
+		t.Errorf("resume: expected 409 for a check that is not paused, got %d", rec.Code)
+	}
+	if rec := adminRequest(t, s, http.MethodGet, "/checks/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get: expected 404 for a missing check, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_AcknowledgesIncident(t *testing.T) {
+	chk := check.New("check-1", check.WithPeriodicSchedule(60), check.WithCommand(&stateCommand{state: check.StateCrit}))
+	_ = chk.Execute()
+	q := memqueue.NewQueue()
+	q.Enqueue(chk)
+	s := New(q)
+
+	rec := adminRequest(t, s, http.MethodPost, "/checks/check-1/ack", `{"author":"alice","comment":"on it","sticky":true}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("ack: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if !chk.Incident.IsAcknowledged() || chk.Incident.Acknowledgement.Author != "alice" || !chk.Incident.Acknowledgement.Sticky {
+		t.Errorf("ack: unexpected acknowledgement %+v", chk.Incident.Acknowledgement)
+	}
+
+	rec = adminRequest(t, s, http.MethodGet, "/checks/check-1", "")
+	var status CheckStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("get: unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	if status.Incident == nil || !status.Incident.IsAcknowledged() || status.LastResult.State != check.StateCrit {
+		t.Errorf("get: expected acknowledged CRIT incident, got %+v", status)
+	}
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestAdminHandler_AcknowledgesWithEnvironmentClock(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	env := check.NewEnvironment(check.WithClock(fixedClock(now)))
+	chk := check.New("check-1", check.WithPeriodicSchedule(60), check.WithCommand(&stateCommand{state: check.StateCrit}), check.WithEnvironment(env))
+	_ = chk.Execute()
+	chk.SetEnvironment(nil)
+	q := memqueue.NewQueue()
+	q.Enqueue(chk)
+	s := New(q, WithEnvironment(env))
+
+	if rec := adminRequest(t, s, http.MethodPost, "/checks/check-1/ack", `{"author":"alice"}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("ack: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if ack := chk.Incident.Acknowledgement; ack == nil || !ack.Time.Equal(now) {
+		t.Errorf("ack: expected acknowledgement at %s, got %+v", now, ack)
+	}
+}
+
+func TestServer_TriggerExecutesCheckNow(t *testing.T) {
+	chk := check.New("check-1", check.WithPeriodicSchedule(3600), check.WithCommand(&stateCommand{state: check.StateOk}))
+	lastCheck := time.Now()
+	chk.LastCheck = &lastCheck // not due for an hour
+	q := memqueue.NewQueue()
+	q.Enqueue(chk)
+
+	s := New(q)
+	if err := s.Trigger(context.Background(), "check-1"); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Trigger(): expected ErrNotRunning before Run, got %v", err)
+	}
+
+	finished := make(chan struct{}, 1)
+	s.OnCheckFinished = func(*check.Check, time.Duration) { finished <- struct{}{} }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	// Run may not have started yet
+	var err error
+	for i := 0; i < 100; i++ {
+		if err = s.Trigger(ctx, "check-1"); !errors.Is(err, ErrNotRunning) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Trigger(): unexpected error: %v", err)
+	}
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Trigger(): check did not execute")
+	}
+}
+
+func TestServer_PendingCheckIsRunning(t *testing.T) {
+	s := New(memqueue.NewQueue())
+	s.done = make(chan struct{}) // as if running
+	chk := check.New("check-1", check.WithPeriodicSchedule(60))
+	s.setPending(chk, true)
+
+	if err := s.Trigger(context.Background(), "check-1"); !errors.Is(err, ErrCheckRunning) {
+		t.Errorf("Trigger(): expected ErrCheckRunning, got %v", err)
+	}
+	if rec := adminRequest(t, s, http.MethodPost, "/checks/check-1/ack", `{"author":"alice"}`); rec.Code != http.StatusConflict {
+		t.Errorf("ack: expected 409 for a pending check, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := adminRequest(t, s, http.MethodGet, "/checks/check-1", ""); rec.Code != http.StatusOK {
+		t.Errorf("get: expected 200 for a pending check, got %d: %s", rec.Code, rec.Body)
+	}
+	if err := s.Pause("check-1"); err != nil {
+		t.Fatalf("Pause(): unexpected error: %v", err)
+	}
+	if s.start(chk, false) {
+		t.Error("start(): expected a check paused while pending to be held")
+	}
+	if _, pending := s.pending["check-1"]; pending {
+		t.Error("start(): expected the check to no longer be pending")
+	}
+}
+
+func TestNewCheckStatus_CopiesIncident(t *testing.T) {
+	chk := check.New("check-1", check.WithPeriodicSchedule(60), check.WithCommand(&stateCommand{state: check.StateCrit}))
+	_ = chk.Execute()
+	chk.Incident.AcknowledgeBy("alice", "")
+
+	status := newCheckStatus(chk)
+	chk.Incident.Escalate(check.StateWarn, "")
+	chk.Incident.Acknowledgement.Author = "bob"
+
+	if len(status.Incident.Timeline) != 2 || len(status.Incident.StateChanges) != 1 {
+		t.Errorf("expected the status incident to keep its timeline and state changes, got %+v", status.Incident)
+	}
+	if &status.Incident.Timeline[0] == &chk.Incident.Timeline[0] || &status.Incident.StateChanges[0] == &chk.Incident.StateChanges[0] {
+		t.Error("expected the status incident not to share its slices with the check")
+	}
+	if status.Incident.Acknowledgement.Author != "alice" {
+		t.Errorf("expected the status acknowledgement to be copied, got %+v", status.Incident.Acknowledgement)
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"github.com/seankndy/gopoller/check"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType is the type of an Event.
+type EventType uint8
+
+const (
+	// EventCheckDequeued is published when a check is taken from the queue.
+	EventCheckDequeued EventType = iota
+	// EventCheckStarted is published just prior to a check executing.
+	EventCheckStarted
+	// EventCheckFinished is published after a check executes, with its Result and Duration.
+	EventCheckFinished
+	// EventCheckErrored is published when a check's execution returns an error, with the Err.
+	EventCheckErrored
+	// EventCheckTimedOut is published when a check's command is abandoned, with the timeout as the Duration.
+	EventCheckTimedOut
+	// EventCheckReEnqueued is published when a check is put back into the queue after executing.
+	EventCheckReEnqueued
+	// EventIncidentOpened is published when a check's execution opens an Incident.
+	EventIncidentOpened
+	// EventIncidentResolved is published when a check's execution resolves its Incident.
+	EventIncidentResolved
+	// EventIncidentAcknowledged is published when an Incident is acknowledged through the server.
+	EventIncidentAcknowledged
+	// EventHandlerFailed is published for each of a check's Handlers whose Process() failed, with the Handler and
+	// Err.
+	EventHandlerFailed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCheckDequeued:
+		return "CHECK_DEQUEUED"
+	case EventCheckStarted:
+		return "CHECK_STARTED"
+	case EventCheckFinished:
+		return "CHECK_FINISHED"
+	case EventCheckErrored:
+		return "CHECK_ERRORED"
+	case EventCheckTimedOut:
+		return "CHECK_TIMED_OUT"
+	case EventCheckReEnqueued:
+		return "CHECK_REENQUEUED"
+	case EventIncidentOpened:
+		return "INCIDENT_OPENED"
+	case EventIncidentResolved:
+		return "INCIDENT_RESOLVED"
+	case EventIncidentAcknowledged:
+		return "INCIDENT_ACKNOWLEDGED"
+	case EventHandlerFailed:
+		return "HANDLER_FAILED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Event is something that happened to a check while the server ran it.  Only the fields relevant to the event's Type
+// are set.
+type Event struct {
+	Type    EventType
+	Time    time.Time
+	CheckId string
+
+	// Command is the type of the check's Command (see check.CommandType).
+	Command string
+
+	Result   *check.Result
+	Incident *check.Incident
+	Duration time.Duration
+	Err      error
+
+	// Handler is the type of the failed Handler (see check.HandlerType).
+	Handler string
+}
+
+// newEvent creates an Event of type t for chk.
+func newEvent(t EventType, chk *check.Check) Event {
+	return Event{
+		Type:    t,
+		Time:    time.Now(),
+		CheckId: chk.Id,
+		Command: check.CommandType(chk.Command),
+	}
+}
+
+// withIncident sets a copy of incident on the Event, as the check keeps changing the original.
+func (e Event) withIncident(incident *check.Incident) Event {
+	if incident != nil {
+		i := *incident
+		e.Incident = &i
+	}
+	return e
+}
+
+// Subscription receives the server's Events from Subscribe.
+type Subscription struct {
+	// Events receives the subscribed Events.  It is closed by Unsubscribe.
+	Events <-chan Event
+
+	events  chan Event
+	types   map[EventType]bool
+	dropped atomic.Uint64
+	bus     *eventBus
+}
+
+// Unsubscribe stops the Subscription receiving Events and closes its Events channel.
+func (sub *Subscription) Unsubscribe() {
+	sub.bus.unsubscribe(sub)
+}
+
+// Dropped returns the number of Events that were discarded because the Subscription's buffer was full.
+func (sub *Subscription) Dropped() uint64 {
+	return sub.dropped.Load()
+}
+
+// wants returns true if the Subscription subscribed to Events of type t.
+func (sub *Subscription) wants(t EventType) bool {
+	return len(sub.types) == 0 || sub.types[t]
+}
+
+// eventBus delivers Events to Subscriptions without blocking the publisher.
+type eventBus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[*Subscription]struct{})}
+}
+
+func (b *eventBus) subscribe(buffer int, types []EventType) *Subscription {
+	events := make(chan Event, buffer)
+	sub := &Subscription{Events: events, events: events, types: make(map[EventType]bool), bus: b}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+func (b *eventBus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.events)
+	}
+}
+
+// publish delivers e to every Subscription that wants it and has room in its buffer.
+func (b *eventBus) publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if !sub.wants(e.Type) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe returns a Subscription receiving the server's Events of the given types, or of every type if none are
+// given.  Events are delivered without blocking the server: once buffer Events are waiting to be received, further
+// Events are dropped (see Subscription.Dropped).  Each subscriber receives its own copy of every Event.
+func (s *Server) Subscribe(buffer int, types ...EventType) *Subscription {
+	return s.events.subscribe(buffer, types)
+}
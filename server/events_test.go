@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/memqueue"
+	"testing"
+	"time"
+)
+
+func receiveEvents(sub *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case e := <-sub.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func TestServer_ExecutePublishesEvents(t *testing.T) {
+	s := New(memqueue.NewQueue())
+	all := s.Subscribe(20)
+	incidents := s.Subscribe(20, EventIncidentOpened, EventIncidentResolved)
+
+	chk := check.New("check-1",
+		check.WithCommand(&stateCommand{state: check.StateCrit}),
+		check.WithHandlers([]check.Handler{&failingHandler{}}),
+	)
+	s.execute(context.Background(), chk)
+
+	events := receiveEvents(all)
+	got := eventTypes(events)
+	want := []EventType{EventCheckStarted, EventIncidentOpened, EventHandlerFailed, EventCheckErrored, EventCheckFinished}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected events %v, got %v", want, got)
+		}
+	}
+	for _, e := range events {
+		if e.CheckId != "check-1" || e.Command != "*server.stateCommand" {
+			t.Errorf("%s: unexpected check %s/%s", e.Type, e.CheckId, e.Command)
+		}
+	}
+	if events[1].Incident == nil || events[1].Incident == chk.Incident {
+		t.Errorf("%s: expected a copy of the check's incident", events[1].Type)
+	}
+	if events[2].Handler != "*server.failingHandler" || events[2].Err == nil {
+		t.Errorf("%s: unexpected handler %s/%v", events[2].Type, events[2].Handler, events[2].Err)
+	}
+	if events[4].Result != chk.LastResult {
+		t.Errorf("%s: expected the check's result", events[4].Type)
+	}
+
+	chk.Command = &stateCommand{state: check.StateOk}
+	chk.Handlers = nil
+	s.execute(context.Background(), chk)
+
+	got = eventTypes(receiveEvents(incidents))
+	if len(got) != 2 || got[0] != EventIncidentOpened || got[1] != EventIncidentResolved {
+		t.Errorf("expected incident to open then resolve, got %v", got)
+	}
+}
+
+func TestServer_AcknowledgePublishesEvent(t *testing.T) {
+	q := memqueue.NewQueue()
+	s := New(q)
+	chk := check.New("check-1", check.WithCommand(&stateCommand{state: check.StateCrit}), check.WithPeriodicSchedule(60))
+	s.execute(context.Background(), chk)
+	q.Enqueue(chk)
+
+	sub := s.Subscribe(1, EventIncidentAcknowledged)
+	if err := s.Acknowledge("check-1", "alice", "looking"); err != nil {
+		t.Fatalf("Acknowledge(): unexpected error: %v", err)
+	}
+
+	events := receiveEvents(sub)
+	if len(events) != 1 || events[0].Incident == nil || !events[0].Incident.IsAcknowledged() {
+		t.Errorf("expected an acknowledged incident event, got %v", events)
+	}
+}
+
+func TestServer_RunPublishesQueueEvents(t *testing.T) {
+	q := memqueue.NewQueue()
+	q.Enqueue(check.New("check-1",
+		check.WithCommand(&stateCommand{state: check.StateOk}),
+		check.WithPeriodicSchedule(60),
+	))
+	s := New(q)
+	sub := s.Subscribe(10, EventCheckDequeued, EventCheckReEnqueued)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer cancel()
+		for i := 0; i < 2; i++ {
+			select {
+			case e := <-sub.Events:
+				if want := []EventType{EventCheckDequeued, EventCheckReEnqueued}[i]; e.Type != want {
+					t.Errorf("expected %s, got %s", want, e.Type)
+				}
+			case <-time.After(3 * time.Second):
+				t.Error("timed out waiting for events")
+				return
+			}
+		}
+	}()
+	s.Run(ctx)
+}
+
+func TestSubscription_DropsEventsWhenFull(t *testing.T) {
+	s := New(memqueue.NewQueue())
+	sub := s.Subscribe(1)
+
+	chk := check.New("check-1")
+	s.events.publish(newEvent(EventCheckStarted, chk))
+	s.events.publish(newEvent(EventCheckFinished, chk))
+
+	if sub.Dropped() != 1 {
+		t.Errorf("Dropped(): expected 1, got %d", sub.Dropped())
+	}
+	if e := <-sub.Events; e.Type != EventCheckStarted {
+		t.Errorf("expected first event to be delivered, got %s", e.Type)
+	}
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	s.events.publish(newEvent(EventCheckStarted, chk))
+	if _, ok := <-sub.Events; ok {
+		t.Error("expected Events to be closed after Unsubscribe()")
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// durationBuckets are the upper bounds, in seconds, of the check duration histogram's buckets
+	durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+	// lagBuckets are the upper bounds, in seconds, of the schedule lag histogram's buckets
+	lagBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+)
+
+// metrics are the server's internal metrics, exposed by MetricsHandler.
+type metrics struct {
+	checkDuration *histogramVec
+	checkErrors   *counterVec
+	checkTimeouts *counterVec
+	handlerErrors *counterVec
+	scheduleLag   *histogramVec
+	reEnqueued    *counterVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		checkDuration: newHistogramVec("gopoller_check_duration_seconds", "Time taken to execute checks.", "command", durationBuckets),
+		checkErrors:   newCounterVec("gopoller_check_errors_total", "Check executions that returned an error.", "command"),
+		checkTimeouts: newCounterVec("gopoller_check_timeouts_total", "Check commands abandoned for exceeding their timeout.", "command"),
+		handlerErrors: newCounterVec("gopoller_handler_errors_total", "Handler Process() calls that returned an error.", "handler"),
+		scheduleLag:   newHistogramVec("gopoller_schedule_lag_seconds", "Time between when checks were due and when they were dequeued.", "", lagBuckets),
+		reEnqueued:    newCounterVec("gopoller_checks_reenqueued_total", "Checks re-enqueued after executing.", ""),
+	}
+}
+
+// MetricsHandler returns an http.Handler serving the server's internal metrics in the Prometheus text exposition
+// format:
+//
+//	gopoller_queue_depth                 checks in the queue
+//	gopoller_running_checks              checks executing
+//	gopoller_max_running_checks          MaxRunningChecks
+//	gopoller_paused_checks               paused checks
+//	gopoller_check_duration_seconds      histogram of check execution time by command type
+//	gopoller_check_errors_total          check executions that errored by command type
+//	gopoller_check_timeouts_total        check commands that timed out by command type
+//	gopoller_handler_errors_total        handler errors by handler type
+//	gopoller_schedule_lag_seconds        histogram of how late checks were dequeued
+//	gopoller_checks_reenqueued_total     checks re-enqueued after executing
+func (s *Server) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = s.WriteMetrics(w)
+	})
+}
+
+// WriteMetrics writes the server's internal metrics to w in the Prometheus text exposition format.
+func (s *Server) WriteMetrics(w io.Writer) error {
+	s.mu.Lock()
+	running, paused := len(s.running), len(s.paused)
+	s.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	writeGauge(bw, "gopoller_queue_depth", "Checks in the queue.", float64(s.checkQueue.Count()))
+	writeGauge(bw, "gopoller_running_checks", "Checks executing.", float64(running))
+	writeGauge(bw, "gopoller_max_running_checks", "Maximum number of checks that may execute concurrently.", float64(s.MaxRunningChecks))
+	writeGauge(bw, "gopoller_paused_checks", "Paused checks.", float64(paused))
+	s.metrics.checkDuration.write(bw)
+	s.metrics.checkErrors.write(bw)
+	s.metrics.checkTimeouts.write(bw)
+	s.metrics.handlerErrors.write(bw)
+	s.metrics.scheduleLag.write(bw)
+	s.metrics.reEnqueued.write(bw)
+	return bw.Flush()
+}
+
+func writeGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatFloat(value))
+}
+
+// counterVec is a counter partitioned by the value of a single label (or unpartitioned if label is "").
+type counterVec struct {
+	name, help, label string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newCounterVec(name, help, label string) *counterVec {
+	return &counterVec{name: name, help: help, label: label, values: make(map[string]float64)}
+}
+
+func (c *counterVec) inc(labelValue string) {
+	c.mu.Lock()
+	c.values[labelValue]++
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	if c.label == "" {
+		fmt.Fprintf(w, "%s %s\n", c.name, formatFloat(c.values[""]))
+		return
+	}
+	for _, v := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, labels(c.label, v), formatFloat(c.values[v]))
+	}
+}
+
+// histogram counts observations into cumulative buckets.
+type histogram struct {
+	counts []uint64 // counts[i] is the number of observations <= the i'th bucket
+	sum    float64
+	count  uint64
+}
+
+// histogramVec is a histogram partitioned by the value of a single label (or unpartitioned if label is "").
+type histogramVec struct {
+	name, help, label string
+	buckets           []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+func newHistogramVec(name, help, label string, buckets []float64) *histogramVec {
+	return &histogramVec{name: name, help: help, label: label, buckets: buckets, values: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) observe(labelValue string, v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hist, ok := h.values[labelValue]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[labelValue] = hist
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += v
+	hist.count++
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	if h.label == "" && len(h.values) == 0 {
+		h.values[""] = &histogram{counts: make([]uint64, len(h.buckets))}
+	}
+	for _, v := range sortedKeys(h.values) {
+		hist := h.values[v]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels(h.label, v, "le", formatFloat(upper)), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels(h.label, v, "le", "+Inf"), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels(h.label, v), formatFloat(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels(h.label, v), hist.count)
+	}
+}
+
+// labels formats name/value pairs as a Prometheus label set, skipping pairs with an empty name.
+func labels(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i] != "" {
+			parts = append(parts, pairs[i]+`="`+labelEscaper.Replace(pairs[i+1])+`"`)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/memqueue"
+	"strings"
+	"testing"
+)
+
+type failingHandler struct{}
+
+func (h *failingHandler) Mutate(*check.Check, *check.Result, *check.Incident) {}
+
+func (h *failingHandler) Process(*check.Check, *check.Result, *check.Incident) error {
+	return errors.New("backend down")
+}
+
+func TestServer_WriteMetrics(t *testing.T) {
+	q := memqueue.NewQueue()
+	q.Enqueue(check.New("queued", check.WithPeriodicSchedule(60)))
+	s := New(q, WithMaxRunningChecks(5))
+
+	chk := check.New("check-1",
+		check.WithCommand(&stateCommand{state: check.StateOk}),
+		check.WithHandlers([]check.Handler{&failingHandler{}}),
+	)
+	s.execute(context.Background(), chk)
+	s.metrics.scheduleLag.observe("", 0.2)
+
+	var buf bytes.Buffer
+	if err := s.WriteMetrics(&buf); err != nil {
+		t.Fatalf("WriteMetrics(): unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# TYPE gopoller_queue_depth gauge\ngopoller_queue_depth 1\n",
+		"gopoller_running_checks 0\n",
+		"gopoller_max_running_checks 5\n",
+		"# TYPE gopoller_check_duration_seconds histogram\n",
+		`gopoller_check_duration_seconds_bucket{command="*server.stateCommand",le="+Inf"} 1` + "\n",
+		`gopoller_check_duration_seconds_count{command="*server.stateCommand"} 1` + "\n",
+		`gopoller_check_errors_total{command="*server.stateCommand"} 1` + "\n",
+		`gopoller_handler_errors_total{handler="*server.failingHandler"} 1` + "\n",
+		`gopoller_schedule_lag_seconds_bucket{le="0.1"} 0` + "\n",
+		`gopoller_schedule_lag_seconds_bucket{le="0.5"} 1` + "\n",
+		"gopoller_checks_reenqueued_total 0\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteMetrics(): expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestLabelsEscapesValues(t *testing.T) {
+	if got, want := labels("handler", "a\"b\\c\nd", "le", "1"), `{handler="a\"b\\c\nd",le="1"}`; got != want {
+		t.Errorf("labels(): expected %s, got %s", want, got)
+	}
+	if got := labels("", ""); got != "" {
+		t.Errorf("labels(): expected no label set, got %s", got)
+	}
+}
@@ -2,9 +2,9 @@ package server
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"github.com/seankndy/gopoller/check"
-	"os"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -20,22 +20,94 @@ type Server struct {
 	MaxRunningChecks int
 
 	// Callback triggerred just prior to check execution (useful for logging)
+	//
+	// Deprecated: Subscribe to EventCheckStarted instead.
 	OnCheckExecuting func(chk *check.Check)
 
 	// Callback triggerred if check command errors (useful for logging)
+	//
+	// Deprecated: Subscribe to EventCheckErrored instead.
 	OnCheckErrored func(chk *check.Check, err error)
 
 	// Callback triggered just after a check finishes execution (useful for logging)
+	//
+	// Deprecated: Subscribe to EventCheckFinished instead.
 	OnCheckFinished func(chk *check.Check, runDuration time.Duration)
+
+	// Callback triggered when a check's command is abandoned for exceeding its timeout (useful for logging)
+	//
+	// Deprecated: Subscribe to EventCheckTimedOut instead.
+	OnCheckTimedOut func(chk *check.Check, timeout time.Duration)
+
+	// DefaultCheckTimeout is the execution timeout applied to checks that do not define their own check.Check.Timeout.
+	// Zero means such checks may run indefinitely.
+	DefaultCheckTimeout time.Duration
+
+	// LongRunningThreshold is how long a check may execute before a warning about it is logged
+	LongRunningThreshold time.Duration
+
+	// LongRunningCheckInterval is how often running checks are inspected for exceeding LongRunningThreshold
+	LongRunningCheckInterval time.Duration
+
+	// PollInterval is how long to wait before dequeuing again when the queue has no due checks.  It only applies to
+	// queues that cannot wait for their next check to come due (see check.WaitingQueue).  Intervals shorter than
+	// minPollInterval (including zero) are raised to it.
+	PollInterval time.Duration
+
+	// Logger is what the server logs to (or slog.Default() if nil).  Checks
+	// without a logger of their own are given this one when they execute.
+	Logger *slog.Logger
+
+	// Environment is passed to checks without an Environment of their own
+	// when they execute, providing their Commands and Handlers with
+	// dependencies such as an SNMP getter or pinger.
+	Environment *check.Environment
+
+	// AdminAddr, when set, is the address Run serves the admin API (see
+	// AdminHandler) and internal metrics (see MetricsHandler) on.
+	AdminAddr string
+
+	// mu guards the fields below, which track the checks outside of the queue
+	mu sync.Mutex
+	// running holds the currently executing checks by ID
+	running map[string]*runningCheck
+	// pending holds the IDs of checks taken from the queue that are waiting to start
+	pending map[string]struct{}
+	// paused holds the IDs of paused checks.  A paused check is held here
+	// (rather than in the queue) once it leaves the queue.
+	paused map[string]*check.Check
+	// statuses holds a snapshot of each check taken after it last executed
+	statuses map[string]CheckStatus
+
+	// triggered receives checks to be executed immediately while Run is running
+	triggered chan *check.Check
+	// done is closed when Run returns, and nil while it is not running
+	done chan struct{}
+
+	// metrics are the server's internal metrics (see MetricsHandler)
+	metrics *metrics
+
+	// events delivers the server's Events to its subscribers (see Subscribe)
+	events *eventBus
 }
 
 type Option func(*Server)
 
 func New(checkQueue check.Queue, options ...Option) *Server {
 	server := &Server{
-		checkQueue:       checkQueue,
-		MaxRunningChecks: 100,
-		AutoReEnqueue:    true,
+		checkQueue:               checkQueue,
+		MaxRunningChecks:         100,
+		AutoReEnqueue:            true,
+		LongRunningThreshold:     30 * time.Second,
+		LongRunningCheckInterval: 60 * time.Second,
+		PollInterval:             time.Second,
+		running:                  make(map[string]*runningCheck),
+		pending:                  make(map[string]struct{}),
+		paused:                   make(map[string]*check.Check),
+		statuses:                 make(map[string]CheckStatus),
+		triggered:                make(chan *check.Check),
+		metrics:                  newMetrics(),
+		events:                   newEventBus(),
 	}
 
 	for _, option := range options {
@@ -57,8 +129,63 @@ func WithMaxRunningChecks(n int) Option {
 	}
 }
 
+func WithDefaultCheckTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.DefaultCheckTimeout = timeout
+	}
+}
+
+func WithLongRunningThreshold(threshold time.Duration) Option {
+	return func(s *Server) {
+		s.LongRunningThreshold = threshold
+	}
+}
+
+func WithLongRunningCheckInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.LongRunningCheckInterval = interval
+	}
+}
+
+func WithPollInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.PollInterval = interval
+	}
+}
+
+// WithLogger sets the logger the server and its checks log to.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.Logger = logger
+	}
+}
+
+// WithEnvironment sets the Environment the server's checks run in.
+func WithEnvironment(env *check.Environment) Option {
+	return func(s *Server) {
+		s.Environment = env
+	}
+}
+
+// WithAdminAddr serves the admin API and internal metrics on addr while the
+// server runs.
+func WithAdminAddr(addr string) Option {
+	return func(s *Server) {
+		s.AdminAddr = addr
+	}
+}
+
+func (s *Server) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
 // Run starts the server.  ctx is a context.Context that when cancelled will
-// stop the server after the currently executing checks finish.
+// stop the server after the currently executing checks finish.  ctx is also
+// passed to each check's Command and Handlers, so cancelling it aborts their
+// in-flight I/O.
 func (s *Server) Run(ctx context.Context) {
 	runningLimiter := make(chan struct{}, s.MaxRunningChecks)
 	defer close(runningLimiter)
@@ -67,6 +194,25 @@ func (s *Server) Run(ctx context.Context) {
 
 	var wg sync.WaitGroup
 
+	s.mu.Lock()
+	done := make(chan struct{})
+	s.done = done
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.done = nil
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	if s.AdminAddr != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveAdmin(ctx)
+		}()
+	}
+
 	// launch goroutine that populates pendingCheck channel from queue indefinitely
 	wg.Add(1)
 	go func() {
@@ -78,76 +224,76 @@ func (s *Server) Run(ctx context.Context) {
 		// checks queue and make the system more responsive
 
 		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-
-			var chk *check.Check
-			if len(pendingChecks) < cap(pendingChecks) {
-				chk = s.checkQueue.Dequeue()
-				if chk != nil {
-					pendingChecks <- chk
+			chk := s.dequeue(ctx)
+			if chk == nil {
+				if ctx.Err() != nil {
+					return
 				}
+				continue
 			}
+			s.setPending(chk, true)
 
-			if chk == nil {
-				time.Sleep(1000 * time.Millisecond)
+			// a check put back in the queue below was never dequeued as far as the metrics and subscribers know
+			lag := time.Since(chk.DueAt()).Seconds()
+			select {
+			case pendingChecks <- chk:
+				s.metrics.scheduleLag.observe("", lag)
+				s.events.publish(newEvent(EventCheckDequeued, chk))
+			case <-ctx.Done():
+				s.setPending(chk, false)
+				s.checkQueue.Enqueue(chk)
+				return
 			}
 		}
 	}()
 
-	runningChecks := sync.Map{}
-	longRunningTicker := time.NewTicker(60 * time.Second)
+	longRunningTicker := time.NewTicker(s.LongRunningCheckInterval)
 	defer longRunningTicker.Stop()
 
+	execute := func(chk *check.Check) {
+		runningLimiter <- struct{}{}
+
+		wg.Add(1)
+		go func(chk *check.Check) {
+			defer wg.Done()
+			defer func() {
+				if !s.finish(chk) && s.AutoReEnqueue {
+					s.checkQueue.Enqueue(chk)
+					s.metrics.reEnqueued.inc("")
+					s.events.publish(newEvent(EventCheckReEnqueued, chk))
+				}
+
+				<-runningLimiter
+			}()
+
+			s.execute(ctx, chk)
+		}(chk)
+	}
+
 loop:
 	for {
 		select {
 		case <-ctx.Done():
 			break loop
 		case chk := <-pendingChecks:
-			runningLimiter <- struct{}{}
-			runningChecks.Store(chk.Id, time.Now())
-
-			wg.Add(1)
-			go func(chk *check.Check) {
-				defer wg.Done()
-				defer func() {
-					if s.AutoReEnqueue {
-						s.checkQueue.Enqueue(chk)
-					}
-
-					<-runningLimiter
-					runningChecks.Delete(chk.Id)
-				}()
-
-				onCheckExecuting := s.OnCheckExecuting
-				if onCheckExecuting != nil {
-					onCheckExecuting(chk)
-				}
-				startTime := time.Now()
-				if err := chk.Execute(); err != nil {
-					onCheckErrored := s.OnCheckErrored
-					if onCheckErrored != nil {
-						onCheckErrored(chk, err)
-					}
-				}
-				onCheckFinished := s.OnCheckFinished
-				if onCheckFinished != nil {
-					onCheckFinished(chk, time.Now().Sub(startTime))
-				}
-			}(chk)
+			if s.start(chk, false) {
+				execute(chk)
+			}
+		case chk := <-s.triggered:
+			s.start(chk, true)
+			execute(chk)
 		case <-longRunningTicker.C:
-			runningChecks.Range(func(key, value interface{}) bool {
-				id := key.(string)
-				t := value.(time.Time)
-				if execTime := time.Now().Sub(t); execTime > 30*time.Second {
-					fmt.Fprintf(os.Stderr, "WARNING: Check with ID %s has been executing for >30sec (%d seconds)!\n", id, execTime/time.Second)
+			s.mu.Lock()
+			for id, r := range s.running {
+				if execTime := time.Now().Sub(r.started); execTime > s.LongRunningThreshold {
+					s.logger().Warn("check is long running",
+						slog.String("check_id", id),
+						slog.Duration("elapsed", execTime),
+						slog.Duration("threshold", s.LongRunningThreshold),
+					)
 				}
-				return true
-			})
+			}
+			s.mu.Unlock()
 		}
 	}
 
@@ -155,6 +301,169 @@ loop:
 
 	// put any pending checks back into the queue prior to shut down as they never ran
 	for chk := range pendingChecks {
+		s.setPending(chk, false)
 		s.checkQueue.Enqueue(chk)
 	}
+	// as well as any paused checks being held out of the queue.  they remain paused if the server runs again
+	s.mu.Lock()
+	for id, chk := range s.paused {
+		if chk != nil {
+			s.checkQueue.Enqueue(chk)
+			s.paused[id] = nil
+		}
+	}
+	s.mu.Unlock()
+}
+
+// minPollInterval is the shortest PollInterval used, so that a zero or negative
+// one does not spin dequeuing an empty queue.
+const minPollInterval = 10 * time.Millisecond
+
+// dequeue returns the next due check from the queue, or nil if none are due.  If the queue is a check.WaitingQueue it
+// waits for one to come due (or ctx to be done), otherwise it waits PollInterval when none are due.
+func (s *Server) dequeue(ctx context.Context) *check.Check {
+	if q, ok := s.checkQueue.(check.WaitingQueue); ok {
+		return q.DequeueWait(ctx)
+	}
+
+	if chk := s.checkQueue.Dequeue(); chk != nil {
+		return chk
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(max(s.PollInterval, minPollInterval)):
+	}
+	return nil
+}
+
+// setPending records whether chk has been taken from the queue and is waiting to start.
+func (s *Server) setPending(chk *check.Check, pending bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pending {
+		s.pending[chk.Id] = struct{}{}
+	} else {
+		delete(s.pending, chk.Id)
+	}
+}
+
+// busy returns true if the check with the given ID is executing or waiting to start.  s.mu must be held.
+func (s *Server) busy(id string) bool {
+	_, pending := s.pending[id]
+	return pending || s.running[id] != nil
+}
+
+// start marks chk as running unless it is paused (and not triggered), in
+// which case it is held until it is resumed.  It returns true if chk should
+// execute.
+func (s *Server) start(chk *check.Check, triggered bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pending, chk.Id)
+	if _, paused := s.paused[chk.Id]; paused && !triggered {
+		s.paused[chk.Id] = chk
+		return false
+	}
+	s.running[chk.Id] = &runningCheck{command: check.CommandType(chk.Command), started: time.Now()}
+	return true
+}
+
+// finish records chk's status after it executed.  It returns true if chk is
+// paused, in which case it is held rather than re-enqueued.
+func (s *Server) finish(chk *check.Check) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, chk.Id)
+	s.statuses[chk.Id] = newCheckStatus(chk)
+	if _, paused := s.paused[chk.Id]; paused {
+		s.paused[chk.Id] = chk
+		return true
+	}
+	return false
+}
+
+// now returns the current time of the Environment chk executes in.
+func (s *Server) now(chk *check.Check) time.Time {
+	if chk.HasEnvironment() || s.Environment == nil {
+		return chk.Environment().Now()
+	}
+	return s.Environment.Now()
+}
+
+// execute executes chk with the server's defaults, publishing events, calling the callbacks and logging its outcome.
+func (s *Server) execute(ctx context.Context, chk *check.Check) {
+	if s.Logger != nil && !chk.HasLogger() {
+		chk.SetLogger(s.Logger)
+	}
+	if s.Environment != nil && !chk.HasEnvironment() {
+		chk.SetEnvironment(s.Environment)
+	}
+	s.events.publish(newEvent(EventCheckStarted, chk))
+	onCheckExecuting := s.OnCheckExecuting
+	if onCheckExecuting != nil {
+		onCheckExecuting(chk)
+	}
+	timeout := chk.Timeout
+	if timeout == 0 {
+		timeout = s.DefaultCheckTimeout
+	}
+	incident := chk.Incident
+	wasResolved := incident == nil || incident.IsResolved()
+	startTime := time.Now()
+	err := chk.ExecuteWithTimeout(ctx, timeout)
+	runDuration := time.Now().Sub(startTime)
+	command := check.CommandType(chk.Command)
+	s.metrics.checkDuration.observe(command, runDuration.Seconds())
+	if !wasResolved && incident.IsResolved() {
+		s.events.publish(newEvent(EventIncidentResolved, chk).withIncident(incident))
+	}
+	if chk.Incident != nil && chk.Incident != incident && !chk.Incident.IsResolved() {
+		s.events.publish(newEvent(EventIncidentOpened, chk).withIncident(chk.Incident))
+	}
+	if err != nil {
+		s.metrics.checkErrors.inc(command)
+		for _, he := range check.HandlerErrors(err) {
+			s.metrics.handlerErrors.inc(check.HandlerType(he.Handler))
+			e := newEvent(EventHandlerFailed, chk)
+			e.Handler, e.Err = check.HandlerType(he.Handler), he.Err
+			s.events.publish(e)
+		}
+		e := newEvent(EventCheckErrored, chk)
+		e.Err = err
+		s.events.publish(e)
+		if errors.Is(err, check.ErrTimeout) {
+			s.metrics.checkTimeouts.inc(command)
+			e := newEvent(EventCheckTimedOut, chk)
+			e.Duration = timeout
+			s.events.publish(e)
+			chk.Logger().Warn("check timed out", slog.Duration("timeout", timeout))
+			onCheckTimedOut := s.OnCheckTimedOut
+			if onCheckTimedOut != nil {
+				onCheckTimedOut(chk, timeout)
+			}
+		} else {
+			chk.Logger().Error("check errored", slog.Any("error", err))
+		}
+		onCheckErrored := s.OnCheckErrored
+		if onCheckErrored != nil {
+			onCheckErrored(chk, err)
+		}
+	}
+	if chk.LastResult != nil {
+		chk.Logger().Debug("check finished",
+			slog.String("state", chk.LastResult.State.String()),
+			slog.String("reason", chk.LastResult.ReasonCode),
+			slog.Duration("duration", runDuration),
+		)
+	}
+	e := newEvent(EventCheckFinished, chk)
+	e.Result, e.Duration = chk.LastResult, runDuration
+	s.events.publish(e)
+	onCheckFinished := s.OnCheckFinished
+	if onCheckFinished != nil {
+		onCheckFinished(chk, runDuration)
+	}
 }
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"github.com/seankndy/gopoller/check"
+	"github.com/seankndy/gopoller/memqueue"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pollingQueue hides the memqueue.Queue's check.WaitingQueue methods.
+type pollingQueue struct {
+	check.Queue
+}
+
+func runUntilFinished(t *testing.T, s *Server, timeout time.Duration) time.Duration {
+	t.Helper()
+	sub := s.Subscribe(1, EventCheckFinished)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	start := time.Now()
+	var elapsed time.Duration
+	go func() {
+		defer cancel()
+		select {
+		case <-sub.Events:
+			elapsed = time.Since(start)
+		case <-time.After(timeout):
+			t.Error("timed out waiting for the check to finish")
+		}
+	}()
+	s.Run(ctx)
+	return elapsed
+}
+
+func TestServer_RunWaitsForWaitingQueue(t *testing.T) {
+	q := memqueue.NewQueue()
+	s := New(q, WithPollInterval(time.Hour))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		q.Enqueue(check.New("check-1",
+			check.WithCommand(&stateCommand{state: check.StateOk}),
+			check.WithPeriodicSchedule(60),
+		))
+	}()
+
+	if elapsed := runUntilFinished(t, s, 3*time.Second); elapsed > time.Second {
+		t.Errorf("Run(): expected check to run as soon as it was enqueued, took %v", elapsed)
+	}
+}
+
+func TestServer_RunPollsOtherQueues(t *testing.T) {
+	q := memqueue.NewQueue()
+	s := New(pollingQueue{q}, WithPollInterval(10*time.Millisecond))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		q.Enqueue(check.New("check-1",
+			check.WithCommand(&stateCommand{state: check.StateOk}),
+			check.WithPeriodicSchedule(60),
+		))
+	}()
+
+	runUntilFinished(t, s, 3*time.Second)
+}
+
+// countingQueue counts calls to Dequeue.
+type countingQueue struct {
+	check.Queue
+	dequeues atomic.Int64
+}
+
+func (q *countingQueue) Dequeue() *check.Check {
+	q.dequeues.Add(1)
+	return q.Queue.Dequeue()
+}
+
+func TestServer_RunDoesNotSpinWithoutPollInterval(t *testing.T) {
+	q := &countingQueue{Queue: pollingQueue{memqueue.NewQueue()}}
+	s := New(q, WithPollInterval(0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	if n := q.dequeues.Load(); n > int64(100*time.Millisecond/minPollInterval)+1 {
+		t.Errorf("Run(): expected dequeuing to wait between polls, dequeued %d times in 100ms", n)
+	}
+}
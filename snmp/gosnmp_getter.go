@@ -1,6 +1,7 @@
 package snmp
 
 import (
+	"context"
 	"fmt"
 	"github.com/gosnmp/gosnmp"
 	"time"
@@ -10,7 +11,12 @@ type GoSnmpGetter struct{}
 
 // Get connects to SNMP 'host' and gets the provided oids in chunks, disconnects, and returns an Object slice
 func (c *GoSnmpGetter) Get(host *Host, oids []string) ([]Object, error) {
-	client, err := c.connect(host)
+	return c.GetContext(context.Background(), host, oids)
+}
+
+// GetContext is like Get but aborts the in-flight requests when ctx is done.
+func (c *GoSnmpGetter) GetContext(ctx context.Context, host *Host, oids []string) ([]Object, error) {
+	client, err := c.connect(ctx, host)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +58,7 @@ func (c *GoSnmpGetter) Get(host *Host, oids []string) ([]Object, error) {
 	return objects, nil
 }
 
-func (c *GoSnmpGetter) connect(host *Host) (*gosnmp.GoSNMP, error) {
+func (c *GoSnmpGetter) connect(ctx context.Context, host *Host) (*gosnmp.GoSNMP, error) {
 	var version gosnmp.SnmpVersion
 	switch host.Version {
 	case "1":
@@ -72,6 +78,7 @@ func (c *GoSnmpGetter) connect(host *Host) (*gosnmp.GoSNMP, error) {
 		Retries:            3,
 		Timeout:            3 * time.Second,
 		ExponentialTimeout: false,
+		Context:            ctx,
 	}
 
 	err := client.Connect()
@@ -1,14 +1,33 @@
 package snmp
 
 import (
+	"context"
+	"github.com/seankndy/gopoller/check"
 	"math/big"
 	"strconv"
 )
 
 var (
+	// DefaultGetter is the Getter used when neither a Command nor its Check's
+	// check.Environment provides one.
+	//
+	// Deprecated: provide a Getter with WithGetter instead.
 	DefaultGetter = &GoSnmpGetter{}
 )
 
+// WithGetter provides getter to the SNMP Commands run in a check.Environment.
+func WithGetter(getter Getter) check.EnvOption {
+	return check.WithDependency[Getter](getter)
+}
+
+// EnvironmentGetter returns the Getter provided by env, or DefaultGetter.
+func EnvironmentGetter(env *check.Environment) Getter {
+	if getter, ok := check.Dependency[Getter](env); ok {
+		return getter
+	}
+	return DefaultGetter
+}
+
 type Host struct {
 	Addr      string
 	Port      uint16
@@ -31,6 +50,25 @@ type Getter interface {
 	Get(host *Host, oids []string) ([]Object, error)
 }
 
+// ContextGetter is a Getter that aborts its requests when its context.Context
+// is done.
+type ContextGetter interface {
+	Getter
+	GetContext(ctx context.Context, host *Host, oids []string) ([]Object, error)
+}
+
+// GetContext gets oids from host using getter, passing ctx along if getter is
+// a ContextGetter.
+func GetContext(ctx context.Context, getter Getter, host *Host, oids []string) ([]Object, error) {
+	if ctxGetter, ok := getter.(ContextGetter); ok {
+		return ctxGetter.GetContext(ctx, host, oids)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return getter.Get(host, oids)
+}
+
 // Asn1BER is the type of the SNMP PDU
 type Asn1BER byte
 